@@ -0,0 +1,171 @@
+package address
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/renanrt/lab-go-api/apperrors"
+	"github.com/renanrt/lab-go-api/model"
+)
+
+// Address represents the address used to find taxes
+type Address struct {
+	Country string `json:"country"`
+	State   string `json:"state,omitempty"`
+	City    string `json:"city,omitempty"`
+	Zipcode string `json:"zipcode"`
+	// Zip4 is the ZIP+4 extension of US zipcodes
+	Zip4   string `json:"zip4,omitempty"`
+	Street string `json:"street,omitempty"`
+}
+
+// New creates a normalized and validated address
+func New(country, state, city, zipcode, street string) (Address, error) {
+	a := Address{Country: country, State: state, City: city, Zipcode: zipcode, Street: street}.Normalize()
+	if err := a.Validate(); err != nil {
+		return a, err
+	}
+	return a, nil
+}
+
+// Normalize returns a copy of the address with whitespace and case cleaned up, the country and
+// state converted to their codes, the ZIP+4 extension split from the zipcode, and US street
+// suffixes abbreviated.
+func (a Address) Normalize() Address {
+	n := Address{
+		Country: normalizeCountry(a.Country),
+		City:    titleCase(a.City),
+		Zip4:    clean(a.Zip4),
+	}
+	n.State = normalizeState(n.Country, a.State)
+	n.Zipcode, n.Zip4 = normalizeZipcode(n.Country, a.Zipcode, n.Zip4)
+	n.Street = normalizeStreet(n.Country, a.Street)
+	return n
+}
+
+// postalCodes are the postal code formats by country, after normalization
+var postalCodes = map[string]*regexp.Regexp{
+	"US": regexp.MustCompile(`^\d{5}$`),
+	"CA": regexp.MustCompile(`^[A-Z]\d[A-Z] \d[A-Z]\d$`),
+	"AU": regexp.MustCompile(`^\d{4}$`),
+	"NZ": regexp.MustCompile(`^\d{4}$`),
+	"GB": regexp.MustCompile(`^[A-Z]{1,2}\d[A-Z\d]? \d[A-Z]{2}$`),
+	"IE": regexp.MustCompile(`^[A-Z]\d[\dW] [A-Z\d]{4}$`),
+	"NL": regexp.MustCompile(`^\d{4} [A-Z]{2}$`),
+	"DE": regexp.MustCompile(`^\d{5}$`),
+	"ES": regexp.MustCompile(`^\d{5}$`),
+	"FR": regexp.MustCompile(`^\d{5}$`),
+	"IT": regexp.MustCompile(`^\d{5}$`),
+}
+
+var zip4 = regexp.MustCompile(`^\d{4}$`)
+
+// Validate checks that the country is supported, the state exists, and the zipcode has
+// the format used in the country. The address should be normalized first.
+func (a Address) Validate() error {
+	fields := map[string]string{}
+
+	if !model.IsSupportedCountry(a.Country) {
+		fields["country"] = "is not supported"
+	}
+
+	if states, ok := regions[a.Country]; ok && a.State != "" && !isStateCode(states, a.State) {
+		fields["state"] = fmt.Sprintf("is not a valid state for %s", a.Country)
+	}
+
+	if a.Zipcode == "" {
+		fields["zipcode"] = "is mandatory"
+	} else if format, ok := postalCodes[a.Country]; ok && !format.MatchString(a.Zipcode) {
+		fields["zipcode"] = fmt.Sprintf("is not a valid postal code for %s", a.Country)
+	}
+
+	if a.Zip4 != "" && !zip4.MatchString(a.Zip4) {
+		fields["zip4"] = "must have 4 digits"
+	}
+
+	if len(fields) > 0 {
+		return apperrors.Invalid(apperrors.CodeInvalidAddress, "address", fields)
+	}
+	return nil
+}
+
+func normalizeCountry(country string) string {
+	if code, ok := countryNames[strings.ToUpper(clean(country))]; ok {
+		return code
+	}
+	return model.NormalizeCountry(clean(country))
+}
+
+func normalizeState(country, state string) string {
+	state = strings.ToUpper(clean(state))
+	if code, ok := regions[country][state]; ok {
+		return code
+	}
+	return state
+}
+
+func isStateCode(states map[string]string, code string) bool {
+	for _, c := range states {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeZipcode upper cases the zipcode and applies the country format.
+// US ZIP+4 codes are split, and the space is added to Canadian, UK, Irish and Dutch codes.
+func normalizeZipcode(country, zipcode, zip4 string) (string, string) {
+	zipcode = strings.ToUpper(clean(zipcode))
+	compact := strings.Replace(zipcode, " ", "", -1)
+
+	switch country {
+	case "US":
+		compact = strings.Replace(compact, "-", "", -1)
+		if len(compact) == 9 {
+			return compact[:5], compact[5:]
+		}
+		return compact, zip4
+	case "CA", "IE":
+		if len(compact) == 6 || len(compact) == 7 {
+			return compact[:3] + " " + compact[3:], zip4
+		}
+	case "GB":
+		if len(compact) > 3 {
+			return compact[:len(compact)-3] + " " + compact[len(compact)-3:], zip4
+		}
+	case "NL":
+		if len(compact) == 6 {
+			return compact[:4] + " " + compact[4:], zip4
+		}
+	}
+	return zipcode, zip4
+}
+
+// normalizeStreet title cases the street, and abbreviates the suffix of US streets
+func normalizeStreet(country, street string) string {
+	words := strings.Fields(titleCase(street))
+	if country == "US" && len(words) > 1 {
+		last := len(words) - 1
+		suffix := strings.ToUpper(strings.TrimSuffix(words[last], "."))
+		if abbreviation, ok := streetSuffixes[suffix]; ok {
+			words[last] = abbreviation
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// clean trims and collapses the whitespace
+func clean(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func titleCase(s string) string {
+	words := strings.Fields(strings.ToLower(s))
+	for i, w := range words {
+		r := []rune(w)
+		words[i] = strings.ToUpper(string(r[0])) + string(r[1:])
+	}
+	return strings.Join(words, " ")
+}
@@ -0,0 +1,71 @@
+package address
+
+import (
+	"testing"
+
+	"github.com/renanrt/lab-go-api/apperrors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalize(t *testing.T) {
+	a := Address{
+		Country: " united states ",
+		State:   "california",
+		City:    "  SANTA   monica ",
+		Zipcode: "90401-1234",
+		Street:  "1234  main street.",
+	}.Normalize()
+
+	assert.Equal(t, "US", a.Country)
+	assert.Equal(t, "CA", a.State)
+	assert.Equal(t, "Santa Monica", a.City)
+	assert.Equal(t, "90401", a.Zipcode)
+	assert.Equal(t, "1234", a.Zip4)
+	assert.Equal(t, "1234 Main St", a.Street)
+
+	a = Address{Zipcode: "904011234"}.Normalize()
+	assert.Equal(t, "US", a.Country)
+	assert.Equal(t, "90401", a.Zipcode)
+	assert.Equal(t, "1234", a.Zip4)
+}
+
+func TestNormalizeInternational(t *testing.T) {
+	a := Address{Country: "ca", State: "British Columbia", Zipcode: "v6b1a1"}.Normalize()
+	assert.Equal(t, "BC", a.State)
+	assert.Equal(t, "V6B 1A1", a.Zipcode)
+
+	a = Address{Country: "UK", Zipcode: "sw1a1aa", Street: "10 downing street"}.Normalize()
+	assert.Equal(t, "GB", a.Country)
+	assert.Equal(t, "SW1A 1AA", a.Zipcode)
+	assert.Equal(t, "10 Downing Street", a.Street)
+
+	a = Address{Country: "Netherlands", Zipcode: "1012js"}.Normalize()
+	assert.Equal(t, "NL", a.Country)
+	assert.Equal(t, "1012 JS", a.Zipcode)
+}
+
+func TestValidate(t *testing.T) {
+	_, err := New("US", "CA", "Santa Monica", "90401", "")
+	assert.NoError(t, err)
+	_, err = New("AU", "NSW", "Sydney", "2000", "")
+	assert.NoError(t, err)
+	_, err = New("DE", "", "Berlin", "10115", "")
+	assert.NoError(t, err)
+
+	_, err = New("US", "ZZ", "", "", "")
+	validationErr, ok := err.(*apperrors.Error)
+	assert.True(t, ok)
+	assert.Equal(t, 400, validationErr.StatusCode())
+	assert.Equal(t, apperrors.CodeInvalidAddress, validationErr.Code)
+	assert.Equal(t, map[string]string{
+		"state":   "is not a valid state for US",
+		"zipcode": "is mandatory",
+	}, validationErr.ErrorFields())
+	assert.Equal(t, "invalid address: state is not a valid state for US, zipcode is mandatory", err.Error())
+
+	_, err = New("CA", "ON", "Toronto", "90401", "")
+	assert.Equal(t, map[string]string{"zipcode": "is not a valid postal code for CA"}, err.(*apperrors.Error).Fields)
+
+	_, err = New("ZZ", "", "", "12345", "")
+	assert.Equal(t, map[string]string{"country": "is not supported"}, err.(*apperrors.Error).Fields)
+}
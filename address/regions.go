@@ -0,0 +1,64 @@
+package address
+
+// usStates maps the upper case US state names to their codes
+var usStates = map[string]string{
+	"ALABAMA": "AL", "ALASKA": "AK", "ARIZONA": "AZ", "ARKANSAS": "AR", "CALIFORNIA": "CA",
+	"COLORADO": "CO", "CONNECTICUT": "CT", "DELAWARE": "DE", "DISTRICT OF COLUMBIA": "DC",
+	"FLORIDA": "FL", "GEORGIA": "GA", "HAWAII": "HI", "IDAHO": "ID", "ILLINOIS": "IL",
+	"INDIANA": "IN", "IOWA": "IA", "KANSAS": "KS", "KENTUCKY": "KY", "LOUISIANA": "LA",
+	"MAINE": "ME", "MARYLAND": "MD", "MASSACHUSETTS": "MA", "MICHIGAN": "MI", "MINNESOTA": "MN",
+	"MISSISSIPPI": "MS", "MISSOURI": "MO", "MONTANA": "MT", "NEBRASKA": "NE", "NEVADA": "NV",
+	"NEW HAMPSHIRE": "NH", "NEW JERSEY": "NJ", "NEW MEXICO": "NM", "NEW YORK": "NY",
+	"NORTH CAROLINA": "NC", "NORTH DAKOTA": "ND", "OHIO": "OH", "OKLAHOMA": "OK", "OREGON": "OR",
+	"PENNSYLVANIA": "PA", "RHODE ISLAND": "RI", "SOUTH CAROLINA": "SC", "SOUTH DAKOTA": "SD",
+	"TENNESSEE": "TN", "TEXAS": "TX", "UTAH": "UT", "VERMONT": "VT", "VIRGINIA": "VA",
+	"WASHINGTON": "WA", "WEST VIRGINIA": "WV", "WISCONSIN": "WI", "WYOMING": "WY",
+	"PUERTO RICO": "PR",
+}
+
+// caProvinces maps the upper case Canadian province and territory names to their codes
+var caProvinces = map[string]string{
+	"ALBERTA": "AB", "BRITISH COLUMBIA": "BC", "MANITOBA": "MB", "NEW BRUNSWICK": "NB",
+	"NEWFOUNDLAND AND LABRADOR": "NL", "NOVA SCOTIA": "NS", "NORTHWEST TERRITORIES": "NT",
+	"NUNAVUT": "NU", "ONTARIO": "ON", "PRINCE EDWARD ISLAND": "PE", "QUEBEC": "QC",
+	"QUÉBEC": "QC", "SASKATCHEWAN": "SK", "YUKON": "YT",
+}
+
+// auStates maps the upper case Australian state and territory names to their codes
+var auStates = map[string]string{
+	"AUSTRALIAN CAPITAL TERRITORY": "ACT", "NEW SOUTH WALES": "NSW", "NORTHERN TERRITORY": "NT",
+	"QUEENSLAND": "QLD", "SOUTH AUSTRALIA": "SA", "TASMANIA": "TAS", "VICTORIA": "VIC",
+	"WESTERN AUSTRALIA": "WA",
+}
+
+// regions returns the state names and codes of the countries where states are validated
+var regions = map[string]map[string]string{
+	"US": usStates,
+	"CA": caProvinces,
+	"AU": auStates,
+}
+
+// countryNames maps the upper case country names that are accepted instead of the ISO codes
+var countryNames = map[string]string{
+	"UNITED STATES":            "US",
+	"UNITED STATES OF AMERICA": "US",
+	"CANADA":                   "CA",
+	"AUSTRALIA":                "AU",
+	"NEW ZEALAND":              "NZ",
+	"UNITED KINGDOM":           "GB",
+	"GREAT BRITAIN":            "GB",
+	"GERMANY":                  "DE",
+	"FRANCE":                   "FR",
+	"SPAIN":                    "ES",
+	"ITALY":                    "IT",
+	"IRELAND":                  "IE",
+	"NETHERLANDS":              "NL",
+}
+
+// streetSuffixes maps the upper case street suffixes to the USPS standard abbreviations
+var streetSuffixes = map[string]string{
+	"ALLEY": "Aly", "AVENUE": "Ave", "AV": "Ave", "BOULEVARD": "Blvd", "CIRCLE": "Cir",
+	"COURT": "Ct", "DRIVE": "Dr", "EXPRESSWAY": "Expy", "FREEWAY": "Fwy", "HIGHWAY": "Hwy",
+	"LANE": "Ln", "PARKWAY": "Pkwy", "PLACE": "Pl", "PLAZA": "Plz", "ROAD": "Rd",
+	"SQUARE": "Sq", "STREET": "St", "STR": "St", "TERRACE": "Ter", "TRAIL": "Trl", "WAY": "Way",
+}
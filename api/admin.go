@@ -0,0 +1,47 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/renanrt/lab-go-api/apperrors"
+	"github.com/renanrt/lab-go-api/logging"
+	"github.com/renanrt/lab-go-api/model"
+)
+
+// purgeCache removes the cached lookups of all providers
+func purgeCache(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	service := getService(r)
+	purged := service.PurgeCache()
+	getLogger(r).Info("purged the cached lookups", logging.Fields{"purged": purged})
+
+	RespondWithData(w, r, map[string]int{"purged": purged}, http.StatusOK)
+}
+
+// getProviderStatus returns the status of the providers
+func getProviderStatus(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	service := getService(r)
+
+	RespondWithCollection(w, r, service.ProviderStatus(), http.StatusOK)
+}
+
+// migrateRateChanges schedules the new rates of a JSON array of rate changes
+// in the saved taxes of every affected retailer
+func migrateRateChanges(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var changes []*model.RateChange
+	if err := json.NewDecoder(r.Body).Decode(&changes); err != nil || len(changes) == 0 {
+		RespondWithError(w, r, apperrors.Validation("the body must be a JSON array of rate changes", nil))
+		return
+	}
+
+	service := getService(r)
+	migration, err := service.MigrateRateChanges(r.Context(), changes)
+	if err != nil {
+		RespondWithError(w, r, err)
+		return
+	}
+	getLogger(r).Info("migrated the rate changes", logging.Fields{"taxes": migration.Taxes, "retailers": len(migration.Retailers)})
+
+	RespondWithData(w, r, migration, http.StatusOK)
+}
@@ -1,19 +1,40 @@
 package api
 
 import (
-	"errors"
+	"context"
 	"net/http"
+	"time"
 
 	"github.com/julienschmidt/httprouter"
+	"github.com/renanrt/lab-go-api/address"
+	"github.com/renanrt/lab-go-api/apperrors"
+	"github.com/renanrt/lab-go-api/auth"
+	"github.com/renanrt/lab-go-api/config"
+	"github.com/renanrt/lab-go-api/geo"
+	"github.com/renanrt/lab-go-api/logging"
+	"github.com/renanrt/lab-go-api/model"
+	"github.com/renanrt/lab-go-api/service"
 )
 
-func Serve() error {
-	app, err := NewServer()
+// Serve serves the API with the configuration, logging with the logger. It only returns if there is an error.
+func Serve(cfg config.Config, logger *logging.Logger) error {
+	taxService, err := service.New(cfg.Service, logger)
 	if err != nil {
 		return err
 	}
 
-	return http.ListenAndServe(":"+"8080", app)
+	app, err := NewServer(Dependencies{Service: taxService, Logger: logger, Config: newConfig(cfg)})
+	if err != nil {
+		return err
+	}
+
+	// Jobs that were running when the process stopped continue from their progress
+	if err := taxService.ResumeJobs(context.Background()); err != nil {
+		return err
+	}
+
+	logger.Info("serving the API", logging.Fields{"addr": cfg.Addr(), "env": cfg.Env})
+	return http.ListenAndServe(cfg.Addr(), app)
 }
 
 type HelloWorldHandler struct {
@@ -24,12 +45,46 @@ func (h *HelloWorldHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	h.Handler.ServeHTTP(w, r)
 }
 
-// Serve serves the API. It only returns if there is an error.
-func NewServer() (*HelloWorldHandler, error) {
+// NewServer creates the API handler with its dependencies, the missing ones get their defaults
+func NewServer(deps Dependencies) (*HelloWorldHandler, error) {
+	deps = deps.withDefaults()
+	authenticator, err := newAuthenticator(deps.Config.Auth, deps.Service)
+	if err != nil {
+		return nil, err
+	}
+
+	// retailers can only use their own taxes and jobs, staff must impersonate one, and only
+	// admins can change the data of the retailer they impersonate. API keys are also limited
+	// to their scopes
+	search := auth.All(auth.RetailerAccess, auth.RequireScope(model.ScopeSearch))
+	writeTaxes := auth.All(auth.RetailerWriteAccess, auth.RequireScope(model.ScopeTaxesWrite))
+	listKeys := auth.All(auth.RetailerAccess, auth.RequireScope(model.ScopeKeysManage))
+	manageKeys := auth.All(auth.RetailerWriteAccess, auth.RequireScope(model.ScopeKeysManage))
+	rateChanges := auth.All(auth.RequireRole(auth.RoleRetailer, auth.RoleSupport), auth.RequireScope(model.ScopeSearch))
+	admin := auth.RequireRole(auth.RoleAdmin)
+
 	r := httprouter.New()
-	r.GET("/api/2.0/taxes-groups/search", searchTaxes)
+	r.GET("/api/2.0/taxes-groups/search", authorize(search, searchTaxes))
+	// httprouter reads ":batch" as a parameter, so the custom method is dispatched by searchTaxesAction
+	r.POST("/api/2.0/taxes-groups/search:action", authorize(search, searchTaxesAction))
+	r.POST("/api/2.0/taxes-groups/accept", authorize(writeTaxes, acceptTaxes))
+	r.GET("/api/2.0/taxes", authorize(search, getTaxes))
+	r.POST("/api/2.0/taxes", authorize(writeTaxes, saveTax))
+	r.PUT("/api/2.0/taxes/:id", authorize(writeTaxes, saveTax))
+	r.GET("/api/2.0/rate-changes", authorize(rateChanges, getRateChanges))
+	r.POST("/api/2.0/jobs", authorize(search, submitJob))
+	r.GET("/api/2.0/jobs/:id", authorize(search, getJob))
+	r.DELETE("/api/2.0/jobs/:id", authorize(search, cancelJob))
+	r.GET("/api/2.0/jobs/:id/results", authorize(search, getJobResults))
+	r.POST("/api/2.0/api-keys", authorize(manageKeys, createAPIKey))
+	r.GET("/api/2.0/api-keys", authorize(listKeys, listAPIKeys))
+	r.POST("/api/2.0/api-keys/:id/rotate", authorize(manageKeys, rotateAPIKey))
+	r.DELETE("/api/2.0/api-keys/:id", authorize(manageKeys, revokeAPIKey))
+	r.DELETE("/api/2.0/admin/cache", authorize(admin, purgeCache))
+	r.GET("/api/2.0/admin/providers", authorize(admin, getProviderStatus))
+	r.POST("/api/2.0/admin/migrations", authorize(admin, migrateRateChanges))
 	r.GET("/healthcheck", healthCheck)
-	return &HelloWorldHandler{r}, nil
+	return &HelloWorldHandler{newRequestLogHandler(deps.Logger, newServiceHandler(deps.Service, newAuthHandler(authenticator, r)))}, nil
 }
 func healthCheck(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
 	w.WriteHeader(http.StatusOK)
@@ -39,29 +94,122 @@ func searchTaxes(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
 
 	queryValues := r.URL.Query()
 
-	//retailer := squire.RequestRetailer(r)
 	country := queryValues.Get("country")
 	state := queryValues.Get("state")
 	street := queryValues.Get("street")
 	city := queryValues.Get("city")
 	zipcode := queryValues.Get("zipcode")
 	provider := queryValues.Get("provider")
+	lat := queryValues.Get("lat")
+	lng := queryValues.Get("lng")
 
-	if zipcode == "" {
-		err := errors.New("zipcode is mandatory")
-
+	asOf, err := parseDate(queryValues.Get("as_of"))
+	if err != nil {
 		RespondWithError(w, r, err)
 		return
 	}
 
 	service := getService(r)
-	retailerID := "dummy-retailer-id"
-	obj, err := service.GetTaxesForAddress(provider, retailerID, country, state, city, zipcode, street)
+	retailerID := RequestRetailer(r)
+
+	var candidates []*model.TaxGroup
+	if lat != "" || lng != "" {
+		// Mobile retailers may only know their GPS position
+		var point geo.Point
+		if point, err = geo.ParsePoint(lat, lng); err != nil {
+			RespondWithError(w, r, err)
+			return
+		}
+		candidates, err = service.FindTaxGroupsForPoint(r.Context(), asOf, provider, model.NormalizeCountry(country), point)
+	} else {
+		var addr address.Address
+		if addr, err = address.New(country, state, city, zipcode, street); err != nil {
+			RespondWithError(w, r, err)
+			return
+		}
+		candidates, err = service.FindTaxGroups(r.Context(), asOf, provider, addr)
+	}
+	// The candidates get the vend tax ids of the taxes the retailer already has
+	if err == nil {
+		err = service.ReconcileTaxes(r.Context(), asOf, retailerID, candidates...)
+	}
+
+	if err != nil {
+		RespondWithError(w, r, err)
+		return
+	}
+
+	// Ambiguous addresses also return every candidate, so the POS can ask the retailer to choose
+	if len(candidates) > 1 {
+		RespondWithData(w, r, searchResponse{candidates[0], candidates}, http.StatusOK)
+		return
+	}
 
+	RespondWithData(w, r, candidates[0], http.StatusOK)
+}
+
+// searchResponse is the tax group with the best confidence, plus the candidates of
+// ambiguous addresses. Clients that don't know about candidates keep using the best one.
+type searchResponse struct {
+	*model.TaxGroup
+	Candidates []*model.TaxGroup `json:"candidates,omitempty"`
+}
+
+func getRateChanges(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	queryValues := r.URL.Query()
+
+	from, err := parseDate(queryValues.Get("from"))
+	if err != nil {
+		RespondWithError(w, r, err)
+		return
+	}
+
+	var to time.Time
+	if queryValues.Get("to") != "" {
+		if to, err = parseDate(queryValues.Get("to")); err != nil {
+			RespondWithError(w, r, err)
+			return
+		}
+	}
+
+	// Retailers only see if they are affected, the staff sees every affected retailer
+	retailerID := RequestRetailer(r)
+	if RequestPrincipal(r).HasRole(auth.RoleSupport) {
+		retailerID = service.AllRetailers
+	}
+
+	taxService := getService(r)
+	changes, err := taxService.GetRateChanges(r.Context(), from, to, queryValues.Get("country"), queryValues.Get("state"), retailerID)
 	if err != nil {
 		RespondWithError(w, r, err)
 		return
 	}
 
-	RespondWithData(w, r, obj, http.StatusOK)
+	RespondWithCollection(w, r, changes, http.StatusOK)
+}
+
+// bodyError is the error of a body that can't be decoded: 413 when it's over the limit of
+// http.MaxBytesReader, or a validation error with the message otherwise
+func bodyError(err error, message string) error {
+	// http.MaxBytesReader doesn't have an error type to check
+	if err.Error() == "http: request body too large" {
+		return apperrors.New(apperrors.CodeRequestTooLarge, "the body is too large")
+	}
+	return apperrors.Validation(message, nil)
+}
+
+// parseDate parses a date query parameter, accepting both 2006-01-02 and RFC3339 formats.
+// An empty value means now.
+func parseDate(value string) (time.Time, error) {
+	if value == "" {
+		return time.Now(), nil
+	}
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, apperrors.Validation("dates must be in the format YYYY-MM-DD", nil)
+	}
+	return t, nil
 }
@@ -0,0 +1,94 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/renanrt/lab-go-api/apperrors"
+	"github.com/renanrt/lab-go-api/model"
+	"github.com/renanrt/lab-go-api/store"
+)
+
+type apiKeyRequest struct {
+	Name   string        `json:"name"`
+	Scopes []model.Scope `json:"scopes"`
+}
+
+// apiKeyResponse is an API key without its hash. The key itself is only sent when it's created or rotated
+type apiKeyResponse struct {
+	*model.APIKey
+	Key string `json:"key,omitempty"`
+}
+
+func newAPIKeyResponse(key *model.APIKey, secret string) *apiKeyResponse {
+	c := key.Copy()
+	c.Hash = ""
+	return &apiKeyResponse{APIKey: c, Key: secret}
+}
+
+// createAPIKey creates an API key for a partner integration of the retailer
+func createAPIKey(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	request := apiKeyRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		RespondWithError(w, r, apperrors.Validation("the body must be a JSON object with name and scopes", nil))
+		return
+	}
+
+	service := getService(r)
+	key, secret, err := service.CreateAPIKey(r.Context(), RequestPrincipal(r), request.Name, request.Scopes)
+	if err != nil {
+		RespondWithError(w, r, apiKeyError(err))
+		return
+	}
+
+	RespondWithData(w, r, newAPIKeyResponse(key, secret), http.StatusCreated)
+}
+
+// listAPIKeys returns the API keys of the retailer
+func listAPIKeys(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	service := getService(r)
+	keys, err := service.ListAPIKeys(r.Context(), RequestRetailer(r))
+	if err != nil {
+		RespondWithError(w, r, err)
+		return
+	}
+
+	responses := make([]*apiKeyResponse, 0, len(keys))
+	for _, key := range keys {
+		responses = append(responses, newAPIKeyResponse(key, ""))
+	}
+	RespondWithCollection(w, r, responses, http.StatusOK)
+}
+
+// rotateAPIKey replaces an API key, the previous one stops working
+func rotateAPIKey(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	service := getService(r)
+	key, secret, err := service.RotateAPIKey(r.Context(), RequestPrincipal(r), ps.ByName("id"))
+	if err != nil {
+		RespondWithError(w, r, apiKeyError(err))
+		return
+	}
+
+	RespondWithData(w, r, newAPIKeyResponse(key, secret), http.StatusOK)
+}
+
+// revokeAPIKey revokes an API key
+func revokeAPIKey(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	service := getService(r)
+	key, err := service.RevokeAPIKey(r.Context(), RequestRetailer(r), ps.ByName("id"))
+	if err != nil {
+		RespondWithError(w, r, apiKeyError(err))
+		return
+	}
+
+	RespondWithData(w, r, newAPIKeyResponse(key, ""), http.StatusOK)
+}
+
+// apiKeyError converts store.ErrNotFound to a 404
+func apiKeyError(err error) error {
+	if err == store.ErrNotFound {
+		return apperrors.NotFound("API key not found")
+	}
+	return err
+}
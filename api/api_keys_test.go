@@ -0,0 +1,42 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/renanrt/lab-go-api/auth"
+	"github.com/renanrt/lab-go-api/logging"
+	"github.com/renanrt/lab-go-api/model"
+	"github.com/renanrt/lab-go-api/service"
+	"github.com/renanrt/lab-go-api/store"
+	"github.com/stretchr/testify/assert"
+)
+
+// tests that a key that only manages keys can't create a key with more scopes
+func TestCreateAPIKeyScopeEscalation(t *testing.T) {
+	taxService := &service.Service{Store: store.NewMemoryStore()}
+	_, secret, err := taxService.CreateAPIKey(context.Background(), &auth.Principal{RetailerID: "retailer-1"},
+		"key manager", []model.Scope{model.ScopeKeysManage})
+	assert.NoError(t, err)
+
+	server, err := NewServer(Dependencies{Service: taxService, Logger: logging.Discard})
+	assert.NoError(t, err)
+	create := func(body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("POST", "/api/2.0/api-keys", strings.NewReader(body))
+		r.Header.Set(auth.APIKeyHeader, secret)
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, r)
+		return w
+	}
+
+	w := create(`{"name": "ecommerce", "scopes": ["taxes:write", "search"]}`)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	keys, _ := taxService.ListAPIKeys(context.Background(), "retailer-1")
+	assert.Len(t, keys, 1)
+
+	w = create(`{"name": "key manager 2", "scopes": ["keys:manage"]}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+}
@@ -0,0 +1,224 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/renanrt/lab-go-api/address"
+	"github.com/renanrt/lab-go-api/apperrors"
+	"github.com/renanrt/lab-go-api/auth"
+	"github.com/renanrt/lab-go-api/config"
+	"github.com/renanrt/lab-go-api/geo"
+	"github.com/renanrt/lab-go-api/logging"
+	"github.com/renanrt/lab-go-api/model"
+	"github.com/renanrt/lab-go-api/service"
+	"github.com/renanrt/lab-go-api/service/servicetest"
+	"github.com/renanrt/lab-go-api/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// tests that the handlers use the injected dependencies
+func TestNewServerDependencies(t *testing.T) {
+	s := store.NewMemoryStore()
+	s.SaveTax(context.Background(), &model.Tax{RetailerID: "retailer-1", Name: "California", Rate: 0.0725, Type: model.TaxTypeState})
+
+	server, err := NewServer(Dependencies{
+		Service: &service.Service{Store: s},
+		Config:  Config{Auth: config.Auth{APIKeys: "key-1:retailer-1"}},
+	})
+	assert.NoError(t, err)
+
+	r := httptest.NewRequest("GET", "/api/2.0/taxes", nil)
+	r.Header.Set(auth.APIKeyHeader, "key-1")
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "California")
+
+	w = httptest.NewRecorder()
+	server.ServeHTTP(w, httptest.NewRequest("GET", "/healthcheck", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestNewServerInvalidConfig(t *testing.T) {
+	_, err := NewServer(Dependencies{Config: Config{Auth: config.Auth{APIKeys: "key-1"}}})
+	assert.Error(t, err)
+}
+
+// serveWithMock sends a request with the API key of retailer-1 to a server using the mock
+func serveWithMock(t *testing.T, m *servicetest.MockTaxService, method, target string) *httptest.ResponseRecorder {
+	server, err := NewServer(Dependencies{
+		Service: m,
+		Logger:  logging.Discard,
+		Config:  Config{Auth: config.Auth{APIKeys: "key-1:retailer-1"}},
+	})
+	assert.NoError(t, err)
+
+	r := httptest.NewRequest(method, target, nil)
+	r.Header.Set(auth.APIKeyHeader, "key-1")
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, r)
+	return w
+}
+
+func newTestTaxGroup(city string, rate float64) *model.TaxGroup {
+	taxGroup := &model.TaxGroup{}
+	taxGroup.AddTaxRate(model.NewTaxRate(model.TaxTypeState, "California", 0.0725))
+	taxGroup.AddTaxRate(model.NewTaxRate(model.TaxTypeCity, city, rate))
+	taxGroup.UpdateTotalRate()
+	return taxGroup
+}
+
+func TestSearchTaxes(t *testing.T) {
+	candidates := []*model.TaxGroup{newTestTaxGroup("Santa Monica", 0.01)}
+	m := &servicetest.MockTaxService{}
+	m.On("FindTaxGroups", mock.Anything, mock.AnythingOfType("time.Time"), "",
+		address.Address{Country: "US", State: "CA", City: "Santa Monica", Zipcode: "90401"}).
+		Return(candidates, nil)
+	// the candidates are reconciled with the taxes of the retailer once
+	m.On("ReconcileTaxes", mock.Anything, mock.AnythingOfType("time.Time"), "retailer-1", candidates).
+		Return(nil).Once()
+
+	w := serveWithMock(t, m, "GET", "/api/2.0/taxes-groups/search?country=usa&state=california&city=santa+monica&zipcode=90401")
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"name": "Santa Monica"`)
+	m.AssertExpectations(t)
+}
+
+func TestSearchTaxesAmbiguous(t *testing.T) {
+	m := &servicetest.MockTaxService{}
+	m.On("FindTaxGroups", mock.Anything, mock.AnythingOfType("time.Time"), "",
+		address.Address{Country: "US", State: "CA", Zipcode: "90046"}).
+		Return([]*model.TaxGroup{newTestTaxGroup("Los Angeles", 0.0225), newTestTaxGroup("West Hollywood", 0.0225)}, nil)
+	m.On("ReconcileTaxes", mock.Anything, mock.AnythingOfType("time.Time"), "retailer-1", mock.Anything).Return(nil)
+
+	w := serveWithMock(t, m, "GET", "/api/2.0/taxes-groups/search?state=CA&zipcode=90046")
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	// clients that don't know about candidates receive the best one
+	response := searchResponse{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	if assert.NotNil(t, response.TaxGroup) && assert.Len(t, response.Candidates, 2) {
+		assert.Equal(t, "Los Angeles", response.GetTaxByType(model.TaxTypeCity)[0].Name)
+		assert.Equal(t, "West Hollywood", response.Candidates[1].GetTaxByType(model.TaxTypeCity)[0].Name)
+	}
+	m.AssertExpectations(t)
+}
+
+func TestSearchTaxesByPoint(t *testing.T) {
+	m := &servicetest.MockTaxService{}
+	m.On("FindTaxGroupsForPoint", mock.Anything, mock.AnythingOfType("time.Time"), "offline", "US", geo.Point{Lat: 34.0195, Lng: -118.4912}).
+		Return([]*model.TaxGroup{newTestTaxGroup("Santa Monica", 0.01)}, nil)
+	m.On("ReconcileTaxes", mock.Anything, mock.AnythingOfType("time.Time"), "retailer-1", mock.Anything).Return(nil)
+
+	w := serveWithMock(t, m, "GET", "/api/2.0/taxes-groups/search?provider=offline&lat=34.0195&lng=-118.4912")
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	m.AssertExpectations(t)
+}
+
+// tests that invalid requests are rejected before reaching the service
+func TestSearchTaxesValidation(t *testing.T) {
+	tests := []struct {
+		query string
+		body  string
+		code  apperrors.Code
+	}{
+		{"country=US&state=CA", `"zipcode"`, apperrors.CodeInvalidAddress},
+		{"country=US&state=CA&zipcode=123", `"zipcode"`, apperrors.CodeInvalidAddress},
+		{"country=US&state=CA&zipcode=90401&as_of=tomorrow", "YYYY-MM-DD", apperrors.CodeInvalidRequest},
+		{"lat=91&lng=0", `"lat"`, apperrors.CodeInvalidCoordinates},
+	}
+	for _, test := range tests {
+		m := &servicetest.MockTaxService{}
+		w := serveWithMock(t, m, "GET", "/api/2.0/taxes-groups/search?"+test.query)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, test.query)
+		assert.Contains(t, w.Body.String(), test.body, test.query)
+		assert.Contains(t, w.Body.String(), fmt.Sprintf(`"code": %d`, test.code), test.query)
+		m.AssertNotCalled(t, "FindTaxGroups")
+		m.AssertNotCalled(t, "FindTaxGroupsForPoint")
+	}
+}
+
+func TestSearchTaxesProviderError(t *testing.T) {
+	m := &servicetest.MockTaxService{}
+	m.On("FindTaxGroups", mock.Anything, mock.AnythingOfType("time.Time"), "avalara",
+		address.Address{Country: "US", State: "CA", Zipcode: "90401"}).
+		Return(nil, errors.New("avalara is not available"))
+
+	w := serveWithMock(t, m, "GET", "/api/2.0/taxes-groups/search?provider=avalara&state=CA&zipcode=90401")
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Contains(t, w.Body.String(), `"error"`)
+	assert.NotContains(t, w.Body.String(), "avalara is not available")
+	m.AssertExpectations(t)
+}
+
+// tests that the errors of the apperrors package set the status, code and headers,
+// without the internal details
+func TestSearchTaxesAppErrors(t *testing.T) {
+	tests := []struct {
+		err    error
+		status int
+		header string
+	}{
+		{apperrors.Upstream("avalara", errors.New("POST https://avalara/v2/taxes: 503")), http.StatusBadGateway, ""},
+		{apperrors.RateLimited("too many requests to provider avalara", 1500*time.Millisecond), http.StatusTooManyRequests, "2"},
+		{apperrors.New(apperrors.CodeNoTaxesFound, "no taxes found for the address"), http.StatusNotFound, ""},
+	}
+	for _, test := range tests {
+		m := &servicetest.MockTaxService{}
+		m.On("FindTaxGroups", mock.Anything, mock.AnythingOfType("time.Time"), "avalara",
+			address.Address{Country: "US", State: "CA", Zipcode: "90401"}).
+			Return(nil, test.err)
+
+		w := serveWithMock(t, m, "GET", "/api/2.0/taxes-groups/search?provider=avalara&state=CA&zipcode=90401")
+
+		assert.Equal(t, test.status, w.Code, test.err.Error())
+		assert.Equal(t, test.header, w.Header().Get("Retry-After"), test.err.Error())
+		assert.Contains(t, w.Body.String(), fmt.Sprintf(`"code": %d`, test.err.(*apperrors.Error).Code))
+		assert.NotContains(t, w.Body.String(), "https://avalara")
+	}
+}
+
+// tests that retailers only see if they are affected by a rate change, and the staff sees every retailer
+func TestGetRateChangesAffectedRetailers(t *testing.T) {
+	s := store.NewMemoryStore()
+	s.SaveTax(context.Background(), &model.Tax{RetailerID: "retailer-1", Name: "Tennessee", Rate: 0.07, Type: model.TaxTypeState})
+	s.SaveTax(context.Background(), &model.Tax{RetailerID: "retailer-2", Name: "Tennessee", Rate: 0.07, Type: model.TaxTypeState})
+	server, err := NewServer(Dependencies{
+		Service: &service.Service{Store: s, Calendar: service.NewRateCalendar(&model.RateChange{
+			Country: "US", State: "TN", Name: "Tennessee", Type: model.TaxTypeState,
+			OldRate: 0.07, NewRate: 0.0725, EffectiveDate: time.Now().AddDate(1, 0, 0),
+		})},
+		Logger: logging.Discard,
+		Config: Config{Auth: config.Auth{APIKeys: "key-1:retailer-1,support-key::support"}},
+	})
+	assert.NoError(t, err)
+
+	get := func(key string) string {
+		r := httptest.NewRequest("GET", "/api/2.0/rate-changes?country=US&state=TN", nil)
+		r.Header.Set(auth.APIKeyHeader, key)
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, r)
+		assert.Equal(t, http.StatusOK, w.Code)
+		return w.Body.String()
+	}
+
+	body := get("key-1")
+	assert.Contains(t, body, "retailer-1")
+	assert.NotContains(t, body, "retailer-2")
+
+	body = get("support-key")
+	assert.Contains(t, body, "retailer-1")
+	assert.Contains(t, body, "retailer-2")
+}
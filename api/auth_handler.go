@@ -0,0 +1,117 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/renanrt/lab-go-api/apperrors"
+	"github.com/renanrt/lab-go-api/auth"
+	"github.com/renanrt/lab-go-api/config"
+	"github.com/renanrt/lab-go-api/logging"
+)
+
+// ImpersonateHeader is the header used by support staff to act for a retailer
+const ImpersonateHeader = "X-Impersonate-Retailer"
+
+// authHandler is a middleware http.Handler that authenticates the request, and adds its
+// auth.Principal to the context. Requests without credentials go through anonymously, so
+// public routes still work, and the other routes are wrapped with authorize.
+type authHandler struct {
+	authenticator auth.Authenticator
+	inner         http.Handler
+}
+
+func newAuthHandler(authenticator auth.Authenticator, inner http.Handler) http.Handler {
+	return &authHandler{authenticator, inner}
+}
+
+func (h *authHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	principal, err := h.authenticator.Authenticate(r)
+	switch err {
+	case nil:
+	case auth.ErrNoCredentials:
+		h.inner.ServeHTTP(w, r)
+		return
+	case auth.ErrInvalidCredentials:
+		respondUnauthorized(w, r, apperrors.Unauthorized("invalid credentials"))
+		return
+	default:
+		RespondWithError(w, r, err)
+		return
+	}
+
+	if retailerID := r.Header.Get(ImpersonateHeader); retailerID != "" {
+		if principal, err = principal.Impersonate(retailerID); err != nil {
+			RespondWithError(w, r, apperrors.Forbidden(err.Error()))
+			return
+		}
+	}
+
+	// The logs of the request identify who did it
+	fields := logging.Fields{"subject": principal.Subject}
+	if principal.RetailerID != "" {
+		fields["retailer"] = principal.RetailerID
+	}
+	if principal.Impersonator != "" {
+		fields["impersonator"] = principal.Impersonator
+	}
+	r = withLogFields(r, fields)
+	h.inner.ServeHTTP(w, r.WithContext(auth.NewContext(r.Context(), principal)))
+}
+
+// authorize is the route level policy: requests without a principal get 401,
+// and principals that the policy doesn't allow get 403
+func authorize(policy auth.Policy, handle httprouter.Handle) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+		principal, ok := auth.FromContext(r.Context())
+		if !ok {
+			respondUnauthorized(w, r, apperrors.Unauthorized("authentication required"))
+			return
+		}
+		if !policy(principal) {
+			RespondWithError(w, r, apperrors.Forbidden("you are not allowed to use this operation"))
+			return
+		}
+		handle(w, r, params)
+	}
+}
+
+// RequestPrincipal returns the principal the request was authenticated for.
+// The request must have gone through authorize.
+func RequestPrincipal(r *http.Request) *auth.Principal {
+	principal, _ := auth.FromContext(r.Context())
+	return principal
+}
+
+// RequestRetailer returns the retailer the request was authenticated for, or impersonates.
+// The request must have gone through authorize with auth.RetailerAccess.
+func RequestRetailer(r *http.Request) string {
+	principal, ok := auth.FromContext(r.Context())
+	if !ok {
+		return ""
+	}
+	return principal.RetailerID
+}
+
+func respondUnauthorized(w http.ResponseWriter, r *http.Request, err error) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="lab-go-api"`)
+	RespondWithError(w, r, err)
+}
+
+// newAuthenticator creates the authenticator with the keys of the configuration, and the API keys
+// managed by the retailers
+func newAuthenticator(cfg config.Auth, keys auth.KeyLookup) (auth.Authenticator, error) {
+	jwtKeys, err := auth.ParseJWTKeys(cfg.JWTKeys)
+	if err != nil {
+		return nil, err
+	}
+	apiKeys, err := auth.ParseAPIKeys(cfg.APIKeys)
+	if err != nil {
+		return nil, err
+	}
+	return auth.Chain{
+		auth.NewJWTAuthenticator(jwtKeys),
+		auth.NewStaticKeyAuthenticator(apiKeys),
+		auth.NewStoredKeyAuthenticator(keys),
+	}, nil
+}
@@ -0,0 +1,71 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/renanrt/lab-go-api/auth"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuthHandler(t *testing.T) {
+	authenticator := auth.NewStaticKeyAuthenticator(map[string]*auth.Principal{
+		"retailer-key": {Subject: "retailer", RetailerID: "retailer-1"},
+		"support-key":  {Subject: "support", Role: auth.RoleSupport},
+		"admin-key":    {Subject: "admin", Role: auth.RoleAdmin},
+	})
+
+	writeRetailer := func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		w.Write([]byte(RequestRetailer(r)))
+	}
+	router := httprouter.New()
+	router.GET("/retailer", authorize(auth.RetailerAccess, writeRetailer))
+	router.POST("/retailer", authorize(auth.RetailerWriteAccess, writeRetailer))
+	router.GET("/admin", authorize(auth.RequireRole(auth.RoleAdmin), writeRetailer))
+	router.GET("/public", healthCheck)
+	handler := newAuthHandler(authenticator, router)
+
+	tests := []struct {
+		method      string
+		path        string
+		key         string
+		impersonate string
+		code        int
+		body        string
+	}{
+		{"GET", "/retailer", "retailer-key", "", http.StatusOK, "retailer-1"},
+		{"GET", "/retailer", "", "", http.StatusUnauthorized, "authentication required"},
+		{"GET", "/retailer", "unknown-key", "", http.StatusUnauthorized, "invalid credentials"},
+		{"GET", "/retailer", "support-key", "", http.StatusForbidden, "not allowed"},
+		{"GET", "/retailer", "support-key", "retailer-2", http.StatusOK, "retailer-2"},
+		{"GET", "/retailer", "retailer-key", "retailer-2", http.StatusForbidden, "impersonate"},
+		{"POST", "/retailer", "retailer-key", "", http.StatusOK, "retailer-1"},
+		{"POST", "/retailer", "support-key", "retailer-2", http.StatusForbidden, "not allowed"},
+		{"POST", "/retailer", "admin-key", "retailer-2", http.StatusOK, "retailer-2"},
+		{"GET", "/admin", "retailer-key", "", http.StatusForbidden, "not allowed"},
+		{"GET", "/admin", "support-key", "", http.StatusForbidden, "not allowed"},
+		{"GET", "/admin", "admin-key", "", http.StatusOK, ""},
+		{"GET", "/public", "", "", http.StatusOK, ""},
+		{"GET", "/public", "unknown-key", "", http.StatusUnauthorized, "invalid credentials"},
+	}
+	for _, test := range tests {
+		r := httptest.NewRequest(test.method, test.path, nil)
+		if test.key != "" {
+			r.Header.Set(auth.APIKeyHeader, test.key)
+		}
+		if test.impersonate != "" {
+			r.Header.Set(ImpersonateHeader, test.impersonate)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		name := test.method + " " + test.path + " " + test.key + " " + test.impersonate
+		assert.Equal(t, test.code, w.Code, name)
+		assert.Contains(t, w.Body.String(), test.body, name)
+		if test.code == http.StatusUnauthorized {
+			assert.NotEmpty(t, w.Header().Get("WWW-Authenticate"))
+		}
+	}
+}
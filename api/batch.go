@@ -0,0 +1,110 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/renanrt/lab-go-api/address"
+	"github.com/renanrt/lab-go-api/model"
+	"github.com/renanrt/lab-go-api/service"
+)
+
+// maxBatchBodySize is the max size of the body of a batch search, enough for
+// service.MaxBatchSize addresses
+const maxBatchBodySize = 1 << 20
+
+// batchItem is an address of a batch search. The ID is returned in the result, so the
+// client can match the results with its outlets.
+type batchItem struct {
+	ID string `json:"id,omitempty"`
+	address.Address
+}
+
+// batchItemResponse is the result of an address of a batch search, with either the
+// candidate tax groups or the error for that address
+type batchItemResponse struct {
+	Index  int               `json:"index"`
+	ID     string            `json:"id,omitempty"`
+	Status int               `json:"status"`
+	Data   []*model.TaxGroup `json:"data,omitempty"`
+	Error  *ErrorResponse    `json:"error,omitempty"`
+}
+
+// searchTaxesAction dispatches the custom methods of the search, like "search:batch"
+func searchTaxesAction(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	switch ps.ByName("action") {
+	case ":batch":
+		searchTaxesBatch(w, r, ps)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// searchTaxesBatch searches the tax groups for an array of addresses. Each address has its
+// own status: 200 with the tax group, 300 with the candidates of ambiguous addresses,
+// or the status and payload of its error. Clients accepting application/x-ndjson receive
+// each result as soon as it's available.
+func searchTaxesBatch(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	queryValues := r.URL.Query()
+
+	items := []batchItem{}
+	r.Body = http.MaxBytesReader(w, r.Body, maxBatchBodySize)
+	if err := json.NewDecoder(r.Body).Decode(&items); err != nil {
+		RespondWithError(w, r, bodyError(err, "the body must be a JSON array of addresses"))
+		return
+	}
+
+	asOf, err := parseDate(queryValues.Get("as_of"))
+	if err != nil {
+		RespondWithError(w, r, err)
+		return
+	}
+
+	addresses := make([]address.Address, 0, len(items))
+	for _, item := range items {
+		addresses = append(addresses, item.Address)
+	}
+
+	taxService := getService(r)
+	retailerID := RequestRetailer(r)
+
+	// Streamed results are sent as soon as they are available, so they are not in order
+	if AcceptsNDJSON(r) {
+		stream := RespondWithStream(w, r, http.StatusOK)
+		err := taxService.FindTaxGroupsBatchFunc(r.Context(), asOf, queryValues.Get("provider"), retailerID, addresses, func(result *service.BatchResult) {
+			stream.Send(newBatchItemResponse(items[result.Index], result))
+		})
+		if err != nil {
+			stream.SendError(err)
+			return
+		}
+		stream.Close()
+		return
+	}
+
+	results, err := taxService.FindTaxGroupsBatch(r.Context(), asOf, queryValues.Get("provider"), retailerID, addresses)
+	if err != nil {
+		RespondWithError(w, r, err)
+		return
+	}
+
+	responses := make([]*batchItemResponse, 0, len(results))
+	for _, result := range results {
+		responses = append(responses, newBatchItemResponse(items[result.Index], result))
+	}
+
+	RespondWithCollection(w, r, responses, http.StatusOK)
+}
+
+func newBatchItemResponse(item batchItem, result *service.BatchResult) *batchItemResponse {
+	response := &batchItemResponse{Index: result.Index, ID: item.ID, Status: http.StatusOK, Data: result.Candidates}
+	if result.Err != nil {
+		code, errorResponse := newErrorResponse(result.Err)
+		response.Status = code
+		response.Error = &errorResponse
+	} else if len(result.Candidates) > 1 {
+		response.Status = http.StatusMultipleChoices
+	}
+	return response
+}
@@ -0,0 +1,84 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/renanrt/lab-go-api/apperrors"
+	"github.com/renanrt/lab-go-api/auth"
+	"github.com/renanrt/lab-go-api/config"
+	"github.com/renanrt/lab-go-api/logging"
+	"github.com/renanrt/lab-go-api/service"
+	"github.com/stretchr/testify/assert"
+)
+
+func serveBatch(t *testing.T, body string) *httptest.ResponseRecorder {
+	server, err := NewServer(Dependencies{
+		Service: &service.Service{},
+		Logger:  logging.Discard,
+		Config:  Config{Auth: config.Auth{APIKeys: "key-1:retailer-1"}},
+	})
+	assert.NoError(t, err)
+
+	r := httptest.NewRequest("POST", "/api/2.0/taxes-groups/search:batch", strings.NewReader(body))
+	r.Header.Set(auth.APIKeyHeader, "key-1")
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, r)
+	return w
+}
+
+// tests that the results are in the order of the addresses, each one with its own status
+func TestSearchTaxesBatch(t *testing.T) {
+	w := serveBatch(t, `[
+		{"id": "outlet-1", "country": "US", "state": "CA", "zipcode": "90401"},
+		{"id": "outlet-2", "country": "US", "state": "ZZ", "zipcode": "90401"},
+		{"id": "outlet-3", "country": "US", "zipcode": "37027"}
+	]`)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	response := struct {
+		Data []*batchItemResponse `json:"data"`
+	}{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	if !assert.Len(t, response.Data, 3) {
+		return
+	}
+	for i, item := range response.Data {
+		assert.Equal(t, i, item.Index)
+		assert.Equal(t, fmt.Sprintf("outlet-%d", i+1), item.ID)
+	}
+
+	assert.Equal(t, http.StatusOK, response.Data[0].Status)
+	assert.Len(t, response.Data[0].Data, 1)
+	assert.Nil(t, response.Data[0].Error)
+
+	assert.Equal(t, http.StatusBadRequest, response.Data[1].Status)
+	assert.Empty(t, response.Data[1].Data)
+	if assert.NotNil(t, response.Data[1].Error) {
+		assert.Equal(t, int(apperrors.CodeInvalidAddress), response.Data[1].Error.Code)
+	}
+
+	assert.Equal(t, http.StatusMultipleChoices, response.Data[2].Status)
+	assert.Len(t, response.Data[2].Data, 2)
+}
+
+func TestSearchTaxesBatchTooLarge(t *testing.T) {
+	items := make([]string, service.MaxBatchSize+1)
+	for i := range items {
+		items[i] = `{"country": "US", "state": "CA", "zipcode": "90401"}`
+	}
+	w := serveBatch(t, "["+strings.Join(items, ",")+"]")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "at most 1000 addresses")
+
+	w = serveBatch(t, `[{"country": "US", "state": "CA", "zipcode": "`+strings.Repeat("9", maxBatchBodySize)+`"}]`)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	assert.Contains(t, w.Body.String(), "the body is too large")
+
+	w = serveBatch(t, `{"country": "US"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
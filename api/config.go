@@ -0,0 +1,51 @@
+package api
+
+import (
+	"github.com/renanrt/lab-go-api/config"
+	"github.com/renanrt/lab-go-api/logging"
+	"github.com/renanrt/lab-go-api/provider"
+	"github.com/renanrt/lab-go-api/service"
+	"github.com/renanrt/lab-go-api/store"
+)
+
+// Config is the configuration of the server
+type Config struct {
+	// Addr is the address where the server listens, like ":8080"
+	Addr string
+	// Auth has the keys of the JWT tokens and the static API keys
+	Auth config.Auth
+}
+
+// newConfig creates the configuration of the server from the configuration of the application
+func newConfig(cfg config.Config) Config {
+	return Config{
+		Addr: cfg.Addr(),
+		Auth: cfg.Auth,
+	}
+}
+
+// Dependencies are what the server uses to handle the requests.
+// They are shared by all requests, so caches in the providers are shared too.
+type Dependencies struct {
+	// Service handles the requests. When it's nil, a service is created with the Store and Providers
+	Service service.TaxService
+	// Store where the retailer data is saved, used when Service is nil
+	Store store.Store
+	// Providers available to the service, used when Service is nil
+	Providers *provider.Registry
+	// Logger records the requests, the operations done by the admins and the errors.
+	// The default logger is used when it's nil
+	Logger *logging.Logger
+	Config Config
+}
+
+// withDefaults returns the dependencies with the defaults for the missing ones
+func (d Dependencies) withDefaults() Dependencies {
+	if d.Service == nil {
+		d.Service = &service.Service{Store: d.Store, Providers: d.Providers}
+	}
+	if d.Logger == nil {
+		d.Logger = logging.Default()
+	}
+	return d
+}
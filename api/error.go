@@ -1,10 +1,19 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/renanrt/lab-go-api/logging"
 )
 
+// StatusClientClosedRequest is the status of the requests cancelled by the client, like in nginx.
+// The client doesn't receive it, it's only in the logs.
+const StatusClientClosedRequest = 499
+
 // StatusCode defines an interface that will be used to determine if an error
 // has meta data about the type of status code that a request should return.
 // Errors that can be converted to this type will set the appropriate code
@@ -37,6 +46,12 @@ type ErrorContext interface {
 	ErrorContext() map[string]interface{}
 }
 
+// RetryAfter defines an interface for errors that tell the client how long to wait
+// before retrying the request, like rate limited errors.
+type RetryAfter interface {
+	RetryAfter() time.Duration
+}
+
 // ErrorResponse is the type that defines what an error payload will look like.
 type ErrorResponse struct {
 	Error  string            `json:"error"`
@@ -47,15 +62,48 @@ type ErrorResponse struct {
 // RespondWithError takes an error, and determines the correct response code
 // and body payload. This function is always the last to write to a response.
 func RespondWithError(w http.ResponseWriter, r *http.Request, responseErr error) {
-	w.Header().Add("Content-Type", "application/json")
+	if retryErr, ok := responseErr.(RetryAfter); ok && retryErr.RetryAfter() > 0 {
+		// Retry-After is in seconds, rounded up so the client doesn't retry too early
+		seconds := (retryErr.RetryAfter() + time.Second - 1) / time.Second
+		w.Header().Set("Retry-After", strconv.Itoa(int(seconds)))
+	}
+
+	code, response := newErrorResponse(responseErr)
+	logError(r, code, responseErr)
+
+	// Clients asking for RFC 7807 get a Problem, the others keep the ErrorResponse
+	var payload interface{} = response
+	contentType := "application/json"
+	if AcceptsProblemJSON(r) {
+		payload = newProblem(r, code, response)
+		contentType = ProblemJSONContentType
+	}
+	w.Header().Add("Content-Type", contentType)
+	w.WriteHeader(code)
+
+	// Generate a response body by marshalling the payload to JSON.
+	body, err := json.MarshalIndent(payload, "", "  ")
+	if err != nil {
+		// This just won't happen, but I hate not assigning errors.
+		panic(err)
+	}
 
+	w.Write(body)
+}
+
+// newErrorResponse determines the http status code and the ErrorResponse for an error
+func newErrorResponse(responseErr error) (int, ErrorResponse) {
 	// Determine http status code
 	code := http.StatusInternalServerError
 	if statusCodeErr, ok := responseErr.(StatusCode); ok {
 		// If this is an error which defines a status code, we should use that.
 		code = statusCodeErr.StatusCode()
+	} else if responseErr == context.Canceled {
+		// The client is gone, it's not a failure of the server
+		code = StatusClientClosedRequest
+	} else if responseErr == context.DeadlineExceeded {
+		code = http.StatusGatewayTimeout
 	}
-	w.WriteHeader(code)
 
 	// Create the response object
 	response := ErrorResponse{Error: responseErr.Error()}
@@ -68,19 +116,33 @@ func RespondWithError(w http.ResponseWriter, r *http.Request, responseErr error)
 	// Add a user error code to the response if possible
 	if errorCodeErr, ok := responseErr.(ErrorCode); ok {
 		response.Code = errorCodeErr.ErrorCode()
-	} else {
-		// Create a new private error as a fallback if the provided
-		// error does not implement the ErrorCode interface
-		response.Error = responseErr.Error()
+	} else if code >= http.StatusInternalServerError {
+		// Server errors without a user error code may have private details, like the
+		// errors of the provider apis, so they are only logged
+		response.Error = http.StatusText(code)
 	}
 
-	// Generate a response body by creating an ErrorResponse instance with the
-	// error message, and marshalling it to JSON.
-	body, err := json.MarshalIndent(response, "", "  ")
-	if err != nil {
-		// This just won't happen, but I hate not assigning errors.
-		panic(err)
+	return code, response
+}
+
+// logError logs server errors at error level, with their private details and context, and
+// client errors at debug level, as they are expected
+func logError(r *http.Request, code int, responseErr error) {
+	fields := logging.Fields{}
+	if contextErr, ok := responseErr.(ErrorContext); ok {
+		for key, value := range contextErr.ErrorContext() {
+			fields[key] = value
+		}
+	}
+	fields["status"] = code
+	fields["error"] = responseErr.Error()
+	if loggableErr, ok := responseErr.(ErrorLogger); ok {
+		fields["error"] = loggableErr.LoggableError()
 	}
 
-	w.Write(body)
+	if code >= http.StatusInternalServerError {
+		getLogger(r).Error("request failed", fields)
+	} else {
+		getLogger(r).Debug("request failed", fields)
+	}
 }
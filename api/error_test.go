@@ -0,0 +1,111 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/renanrt/lab-go-api/apperrors"
+	"github.com/renanrt/lab-go-api/logging"
+	"github.com/stretchr/testify/assert"
+)
+
+// respondWithLogger responds with the error through requestLogHandler, and returns the response and the log
+func respondWithLogger(err error, level logging.Level) (*httptest.ResponseRecorder, string) {
+	buf := &bytes.Buffer{}
+	handler := newRequestLogHandler(logging.New(buf, level, logging.FormatHuman), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		RespondWithError(w, r, err)
+	}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/api/2.0/taxes-groups/search", nil))
+	return w, buf.String()
+}
+
+func TestRespondWithErrorLogsServerErrors(t *testing.T) {
+	w, logged := respondWithLogger(errors.New("dial tcp 10.0.0.1:443: connection refused"), logging.LevelError)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.NotContains(t, w.Body.String(), "10.0.0.1")
+	assert.Contains(t, w.Body.String(), "Internal Server Error")
+	assert.Contains(t, logged, "ERROR request failed")
+	assert.Contains(t, logged, `error="dial tcp 10.0.0.1:443: connection refused"`)
+	assert.Contains(t, logged, "method=GET path=/api/2.0/taxes-groups/search")
+	assert.Contains(t, logged, "status=500")
+
+	w, logged = respondWithLogger(apperrors.Upstream("avalara", errors.New("503 from https://avalara")), logging.LevelError)
+	assert.Equal(t, http.StatusBadGateway, w.Code)
+	assert.NotContains(t, w.Body.String(), "https://avalara")
+	assert.Contains(t, logged, "503 from https://avalara")
+	assert.Contains(t, logged, "provider=avalara")
+}
+
+func TestRespondWithErrorLogsClientErrorsInDebug(t *testing.T) {
+	_, logged := respondWithLogger(apperrors.NotFound("job not found"), logging.LevelInfo)
+	assert.NotContains(t, logged, "request failed")
+
+	_, logged = respondWithLogger(apperrors.NotFound("job not found"), logging.LevelDebug)
+	assert.Contains(t, logged, "DEBUG request failed")
+	assert.Contains(t, logged, `error="job not found"`)
+}
+
+// tests that cancelled requests are not logged as server errors, and timeouts are not 500
+func TestRespondWithErrorContext(t *testing.T) {
+	w, logged := respondWithLogger(context.Canceled, logging.LevelDebug)
+	assert.Equal(t, StatusClientClosedRequest, w.Code)
+	assert.Contains(t, logged, "DEBUG request failed")
+	assert.NotContains(t, logged, "ERROR")
+
+	w, logged = respondWithLogger(context.DeadlineExceeded, logging.LevelError)
+	assert.Equal(t, http.StatusGatewayTimeout, w.Code)
+	assert.Contains(t, w.Body.String(), "Gateway Timeout")
+	assert.Contains(t, logged, "ERROR request failed")
+}
+
+func TestRespondWithErrorProblem(t *testing.T) {
+	r := httptest.NewRequest("GET", "/api/2.0/taxes-groups/search?zipcode=1", nil)
+	r.Header.Set("Accept", "application/problem+json, application/json")
+	w := httptest.NewRecorder()
+	RespondWithError(w, r, apperrors.Invalid(apperrors.CodeInvalidAddress, "address", map[string]string{
+		"zipcode": "is not a valid postal code for US",
+		"state":   "is mandatory",
+	}))
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, ProblemJSONContentType, w.Header().Get("Content-Type"))
+	problem := Problem{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem))
+	assert.Equal(t, Problem{
+		Type:     "urn:lab-go-api:error:1001",
+		Title:    "Invalid address",
+		Status:   http.StatusBadRequest,
+		Detail:   "invalid address: state is mandatory, zipcode is not a valid postal code for US",
+		Instance: "/api/2.0/taxes-groups/search",
+		Code:     1001,
+		InvalidParams: []InvalidParam{
+			{Name: "state", Reason: "is mandatory"},
+			{Name: "zipcode", Reason: "is not a valid postal code for US"},
+		},
+	}, problem)
+
+	// errors without a user error code are described by their status
+	w = httptest.NewRecorder()
+	RespondWithError(w, r, errors.New("connection refused"))
+	problem = Problem{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem))
+	assert.Equal(t, "about:blank", problem.Type)
+	assert.Equal(t, "Internal Server Error", problem.Title)
+	assert.Equal(t, http.StatusInternalServerError, problem.Status)
+}
+
+// tests that the clients that don't ask for problem+json keep the ErrorResponse
+func TestRespondWithErrorDefault(t *testing.T) {
+	w := httptest.NewRecorder()
+	RespondWithError(w, httptest.NewRequest("GET", "/", nil), apperrors.NotFound("job not found"))
+
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{"error": "job not found", "code": 3000}`, w.Body.String())
+}
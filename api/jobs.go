@@ -0,0 +1,204 @@
+package api
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/renanrt/lab-go-api/apperrors"
+	"github.com/renanrt/lab-go-api/model"
+	"github.com/renanrt/lab-go-api/store"
+)
+
+// maxJobBodySize is the max size of the body of a job, enough for service.MaxJobSize addresses
+const maxJobBodySize = 32 << 20
+
+// jobCSVColumns are the columns of a CSV job, the header row is mandatory
+var jobCSVColumns = []string{"id", "country", "state", "city", "zipcode", "street"}
+
+// submitJob creates a bulk lookup job from a CSV file or a JSON array of addresses.
+// It responds with 202 and the job, whose progress can be polled at /api/2.0/jobs/:id
+func submitJob(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	queryValues := r.URL.Query()
+
+	asOf, err := parseDate(queryValues.Get("as_of"))
+	if err != nil {
+		RespondWithError(w, r, err)
+		return
+	}
+
+	var items []*model.JobItem
+	r.Body = http.MaxBytesReader(w, r.Body, maxJobBodySize)
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "text/csv" {
+		items, err = parseJobItemsCSV(r.Body)
+	} else if err = json.NewDecoder(r.Body).Decode(&items); err != nil {
+		err = bodyError(err, "the body must be a JSON array of addresses")
+	}
+	if err != nil {
+		RespondWithError(w, r, err)
+		return
+	}
+
+	service := getService(r)
+	retailerID := RequestRetailer(r)
+	job, err := service.SubmitJob(r.Context(), retailerID, queryValues.Get("provider"), asOf, items)
+	if err != nil {
+		RespondWithError(w, r, err)
+		return
+	}
+
+	RespondWithData(w, r, job.Summary(), http.StatusAccepted)
+}
+
+// getJob returns the progress of a job
+func getJob(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	service := getService(r)
+	retailerID := RequestRetailer(r)
+	job, err := service.GetJob(r.Context(), retailerID, ps.ByName("id"))
+	if err != nil {
+		RespondWithError(w, r, jobError(err))
+		return
+	}
+
+	RespondWithData(w, r, job.Summary(), http.StatusOK)
+}
+
+// cancelJob stops a job, keeping the results processed so far
+func cancelJob(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	service := getService(r)
+	retailerID := RequestRetailer(r)
+	job, err := service.CancelJob(r.Context(), retailerID, ps.ByName("id"))
+	if err != nil {
+		RespondWithError(w, r, jobError(err))
+		return
+	}
+
+	RespondWithData(w, r, job.Summary(), http.StatusOK)
+}
+
+// getJobResults downloads the results processed so far, as CSV or NDJSON.
+// The format comes from the format query parameter, or the Accept header.
+func getJobResults(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	service := getService(r)
+	retailerID := RequestRetailer(r)
+	job, err := service.GetJob(r.Context(), retailerID, ps.ByName("id"))
+	if err != nil {
+		RespondWithError(w, r, jobError(err))
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" && strings.Contains(r.Header.Get("Accept"), "text/csv") {
+		format = "csv"
+	}
+
+	switch format {
+	case "csv":
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "job-"+job.ID+".csv"))
+		w.WriteHeader(http.StatusOK)
+		writeJobResultsCSV(w, job)
+	case "", "ndjson":
+		stream := RespondWithStream(w, r, http.StatusOK)
+		for _, result := range job.Results {
+			if err := stream.Send(result); err != nil {
+				return
+			}
+		}
+		stream.Close()
+	default:
+		RespondWithError(w, r, apperrors.Validation("format must be csv or ndjson", nil))
+	}
+}
+
+// jobError converts store.ErrNotFound to a 404
+func jobError(err error) error {
+	if err == store.ErrNotFound {
+		return apperrors.NotFound("job not found")
+	}
+	return err
+}
+
+// parseJobItemsCSV reads the addresses of a CSV file with the jobCSVColumns header
+func parseJobItemsCSV(body io.Reader) ([]*model.JobItem, error) {
+	reader := csv.NewReader(body)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, bodyError(err, "the CSV must have a header row")
+	}
+	columns := map[string]int{}
+	for i, column := range header {
+		columns[strings.ToLower(strings.TrimSpace(column))] = i
+	}
+	if _, ok := columns["zipcode"]; !ok {
+		return nil, apperrors.Validation("the CSV must have the columns "+strings.Join(jobCSVColumns, ","), nil)
+	}
+
+	items := []*model.JobItem{}
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return items, nil
+		}
+		if err != nil {
+			return nil, bodyError(err, fmt.Sprintf("invalid CSV at line %d: %v", line, err))
+		}
+		value := func(column string) string {
+			if i, ok := columns[column]; ok && i < len(record) {
+				return record[i]
+			}
+			return ""
+		}
+		items = append(items, &model.JobItem{
+			ID:      value("id"),
+			Country: value("country"),
+			State:   value("state"),
+			City:    value("city"),
+			Zipcode: value("zipcode"),
+			Street:  value("street"),
+		})
+	}
+}
+
+// writeJobResultsCSV writes a row for each candidate tax group of the results,
+// or a single row with the error
+func writeJobResultsCSV(w io.Writer, job *model.Job) error {
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"id", "status", "candidate", "total_rate", "confidence", "jurisdictions", "rates", "error"})
+
+	for _, result := range job.Results {
+		status := strconv.Itoa(result.Status)
+		if len(result.Candidates) == 0 {
+			writer.Write([]string{result.ID, status, "", "", "", "", "", result.Error})
+			continue
+		}
+		for i, tg := range result.Candidates {
+			rates := []string{}
+			for _, tr := range tg.Rates {
+				rates = append(rates, tr.Name+":"+strconv.FormatFloat(tr.Rate, 'f', -1, 64))
+			}
+			writer.Write([]string{
+				result.ID,
+				status,
+				strconv.Itoa(i + 1),
+				strconv.FormatFloat(tg.TotalRate, 'f', -1, 64),
+				strconv.FormatFloat(tg.Confidence, 'f', -1, 64),
+				strings.Join(tg.Jurisdictions, ";"),
+				strings.Join(rates, ";"),
+				"",
+			})
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
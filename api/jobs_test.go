@@ -0,0 +1,34 @@
+package api
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/renanrt/lab-go-api/apperrors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseJobItemsCSV(t *testing.T) {
+	items, err := parseJobItemsCSV(strings.NewReader("id,country,state,zipcode\noutlet-1,US,CA,90401\noutlet-2,US,TN,37027\n"))
+	assert.NoError(t, err)
+	if assert.Len(t, items, 2) {
+		assert.Equal(t, "outlet-2", items[1].ID)
+		assert.Equal(t, "37027", items[1].Zipcode)
+	}
+
+	_, err = parseJobItemsCSV(strings.NewReader("id,country,state\noutlet-1,US,CA\n"))
+	assert.Equal(t, http.StatusBadRequest, err.(*apperrors.Error).StatusCode())
+
+	// bodies over the limit, in the header and in the rows
+	limited := func(body string, limit int64) io.Reader {
+		return http.MaxBytesReader(httptest.NewRecorder(), ioutil.NopCloser(strings.NewReader(body)), limit)
+	}
+	_, err = parseJobItemsCSV(limited("id,country,state,zipcode\n", 10))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, err.(*apperrors.Error).StatusCode())
+	_, err = parseJobItemsCSV(limited("id,country,state,zipcode\noutlet-1,US,CA,90401\noutlet-2,US,TN,37027\n", 50))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, err.(*apperrors.Error).StatusCode())
+}
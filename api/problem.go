@@ -0,0 +1,62 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/renanrt/lab-go-api/apperrors"
+)
+
+// ProblemJSONContentType is the content type of RFC 7807 error responses
+const ProblemJSONContentType = "application/problem+json"
+
+// Problem is an RFC 7807 error payload, sent to the clients that accept application/problem+json.
+// The other clients get an ErrorResponse.
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	// Code is the user error code, the same of ErrorResponse
+	Code          int            `json:"code,omitempty"`
+	InvalidParams []InvalidParam `json:"invalid-params,omitempty"`
+}
+
+// InvalidParam is an invalid field of the request and the problem with it
+type InvalidParam struct {
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+}
+
+// AcceptsProblemJSON checks if the client asked for RFC 7807 error responses
+func AcceptsProblemJSON(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), ProblemJSONContentType)
+}
+
+// newProblem converts the ErrorResponse of an error to a Problem. Errors with a user error
+// code have a type for the code, the others are only described by their status.
+func newProblem(r *http.Request, code int, response ErrorResponse) Problem {
+	problem := Problem{
+		Type:     "about:blank",
+		Title:    http.StatusText(code),
+		Status:   code,
+		Detail:   response.Error,
+		Instance: r.URL.Path,
+		Code:     response.Code,
+	}
+	if response.Code != 0 {
+		problem.Type = fmt.Sprintf("urn:lab-go-api:error:%d", response.Code)
+		problem.Title = apperrors.Code(response.Code).Title()
+	}
+
+	for name, reason := range response.Fields {
+		problem.InvalidParams = append(problem.InvalidParams, InvalidParam{Name: name, Reason: reason})
+	}
+	sort.Slice(problem.InvalidParams, func(i, j int) bool {
+		return problem.InvalidParams[i].Name < problem.InvalidParams[j].Name
+	})
+	return problem
+}
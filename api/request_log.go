@@ -0,0 +1,84 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/renanrt/lab-go-api/logging"
+	"github.com/renanrt/lab-go-api/store"
+)
+
+// RequestIDHeader is the header with the id of the request. Ids sent by the clients or proxies
+// are kept, so their logs can be matched with ours.
+const RequestIDHeader = "X-Request-Id"
+
+// maxRequestIDLength limits the ids sent by the clients, as they are written to the logs
+const maxRequestIDLength = 64
+
+// requestLogHandler is a middleware http.Handler that adds a logger with the request id, method,
+// path and provider to the context, and logs each request when it's done.
+type requestLogHandler struct {
+	logger *logging.Logger
+	inner  http.Handler
+}
+
+func newRequestLogHandler(logger *logging.Logger, inner http.Handler) http.Handler {
+	return &requestLogHandler{logger, inner}
+}
+
+func (h *requestLogHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	id := r.Header.Get(RequestIDHeader)
+	if id == "" || len(id) > maxRequestIDLength {
+		id = store.NewID()
+	}
+	w.Header().Set(RequestIDHeader, id)
+
+	fields := logging.Fields{"request_id": id, "method": r.Method, "path": r.URL.Path}
+	if provider := r.URL.Query().Get("provider"); provider != "" {
+		fields["provider"] = provider
+	}
+	logger := h.logger.With(fields)
+	sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+	start := time.Now()
+	h.inner.ServeHTTP(sw, r.WithContext(logging.NewContext(r.Context(), logger)))
+
+	logger.Info("request served", logging.Fields{"status": sw.status, "duration_ms": time.Since(start).Nanoseconds() / int64(time.Millisecond)})
+}
+
+// statusWriter records the status code of the response. It's an http.Flusher like the
+// writers of the http server, so streamed responses are still flushed.
+type statusWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	if !w.wroteHeader {
+		w.status = code
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush sends the buffered data to the client, if the wrapped writer supports it
+func (w *statusWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// getLogger retrieves the logger of the request, with the fields of the request
+func getLogger(r *http.Request) *logging.Logger {
+	return logging.FromContext(r.Context())
+}
+
+// withLogFields adds fields to the logger of the request
+func withLogFields(r *http.Request, fields logging.Fields) *http.Request {
+	return r.WithContext(logging.NewContext(r.Context(), getLogger(r).With(fields)))
+}
@@ -0,0 +1,75 @@
+package api
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/renanrt/lab-go-api/auth"
+	"github.com/renanrt/lab-go-api/config"
+	"github.com/renanrt/lab-go-api/logging"
+	"github.com/renanrt/lab-go-api/service/servicetest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestLogHandler(t *testing.T) {
+	buf := &bytes.Buffer{}
+	server, err := NewServer(Dependencies{
+		Service: &servicetest.MockTaxService{},
+		Logger:  logging.New(buf, logging.LevelInfo, logging.FormatHuman),
+		Config:  Config{Auth: config.Auth{APIKeys: "key-1:retailer-1"}},
+	})
+	assert.NoError(t, err)
+
+	r := httptest.NewRequest("GET", "/api/2.0/taxes-groups/search?provider=offline", nil)
+	r.Header.Set(auth.APIKeyHeader, "key-1")
+	r.Header.Set(RequestIDHeader, "request-1")
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, r)
+
+	assert.Equal(t, "request-1", w.Header().Get(RequestIDHeader))
+	assert.Contains(t, buf.String(), "INFO  request served")
+	assert.Contains(t, buf.String(), "method=GET path=/api/2.0/taxes-groups/search provider=offline request_id=request-1")
+	assert.Contains(t, buf.String(), "status=400")
+
+	// requests without an id get a new one
+	w = httptest.NewRecorder()
+	server.ServeHTTP(w, httptest.NewRequest("GET", "/healthcheck", nil))
+	assert.NotEmpty(t, w.Header().Get(RequestIDHeader))
+}
+
+// tests that the retailer of the request is added to the logs by the auth handler
+func TestRequestLogRetailer(t *testing.T) {
+	buf := &bytes.Buffer{}
+	handler := newRequestLogHandler(logging.New(buf, logging.LevelInfo, logging.FormatHuman),
+		newAuthHandler(newTestAuthenticator(t), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			getLogger(r).Info("handled")
+		})))
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set(auth.APIKeyHeader, "key-1")
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+
+	assert.Contains(t, buf.String(), "handled")
+	assert.Contains(t, buf.String(), "retailer=retailer-1")
+}
+
+// tests that streamed responses are still flushed through the status writer
+func TestRequestLogHandlerFlush(t *testing.T) {
+	handler := newRequestLogHandler(logging.Discard, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		stream := RespondWithStream(w, r, http.StatusOK)
+		stream.Send(map[string]int{"index": 0})
+	}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	assert.True(t, w.Flushed)
+	assert.Equal(t, "{\"index\":0}\n", w.Body.String())
+}
+
+func newTestAuthenticator(t *testing.T) auth.Authenticator {
+	authenticator, err := newAuthenticator(config.Auth{APIKeys: "key-1:retailer-1"}, nil)
+	assert.NoError(t, err)
+	return authenticator
+}
@@ -27,7 +27,7 @@ func (h *serviceHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 }
 
 // getService retrieves a service.TaxService from the context. The request that's passed in must
-// have gone through dbHandler.
+// have gone through serviceHandler.
 func getService(r *http.Request) service.TaxService {
-	return service.TaxService{}
+	return r.Context().Value(serviceKey).(service.TaxService)
 }
@@ -0,0 +1,82 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// NDJSONContentType is the content type of newline delimited JSON responses
+const NDJSONContentType = "application/x-ndjson"
+
+// StreamResponder writes newline delimited JSON values as they become available,
+// flushing each one to the client. The status code is only sent with the first value,
+// so errors that happen after that are encoded in-band as a StreamError line.
+type StreamResponder struct {
+	w       http.ResponseWriter
+	r       *http.Request
+	code    int
+	encoder *json.Encoder
+	started bool
+}
+
+// StreamError is the line written when an error happens in the middle of a stream
+type StreamError struct {
+	Status int           `json:"status"`
+	Error  ErrorResponse `json:"error"`
+}
+
+// RespondWithStream creates a StreamResponder that will respond with the status code
+func RespondWithStream(w http.ResponseWriter, r *http.Request, code int) *StreamResponder {
+	return &StreamResponder{w: w, r: r, code: code, encoder: json.NewEncoder(w)}
+}
+
+// AcceptsNDJSON checks if the client asked for a newline delimited JSON response
+func AcceptsNDJSON(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), NDJSONContentType) || r.URL.Query().Get("format") == "ndjson"
+}
+
+// Send writes a value as a line and flushes it
+func (s *StreamResponder) Send(data interface{}) error {
+	s.start()
+	if err := s.encoder.Encode(data); err != nil {
+		return err
+	}
+	s.flush()
+	return nil
+}
+
+// SendError responds with the error. When nothing was sent yet it's a normal error response,
+// with its status code. Otherwise, it's written as a StreamError line.
+func (s *StreamResponder) SendError(responseErr error) error {
+	if !s.started {
+		s.started = true
+		RespondWithError(s.w, s.r, responseErr)
+		return nil
+	}
+
+	code, response := newErrorResponse(responseErr)
+	logError(s.r, code, responseErr)
+	return s.Send(StreamError{Status: code, Error: response})
+}
+
+// Close sends the headers if nothing was sent, so an empty stream still has its status code
+func (s *StreamResponder) Close() {
+	s.start()
+	s.flush()
+}
+
+func (s *StreamResponder) start() {
+	if s.started {
+		return
+	}
+	s.started = true
+	s.w.Header().Set("Content-Type", NDJSONContentType)
+	s.w.WriteHeader(s.code)
+}
+
+func (s *StreamResponder) flush() {
+	if flusher, ok := s.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,46 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/renanrt/lab-go-api/apperrors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStreamResponder(t *testing.T) {
+	w := httptest.NewRecorder()
+	stream := RespondWithStream(w, httptest.NewRequest("GET", "/", nil), http.StatusOK)
+
+	assert.NoError(t, stream.Send(map[string]int{"index": 0}))
+	assert.True(t, w.Flushed)
+	assert.NoError(t, stream.SendError(errors.New("provider is down")))
+	stream.Close()
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, NDJSONContentType, w.Header().Get("Content-Type"))
+	assert.Equal(t, "{\"index\":0}\n{\"status\":500,\"error\":{\"error\":\"Internal Server Error\"}}\n", w.Body.String())
+}
+
+// tests that an error before anything was sent uses the status code
+func TestStreamResponderErrorBeforeStart(t *testing.T) {
+	w := httptest.NewRecorder()
+	stream := RespondWithStream(w, httptest.NewRequest("GET", "/", nil), http.StatusOK)
+
+	assert.NoError(t, stream.SendError(apperrors.Validation("a batch can have at most 1000 addresses", nil)))
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+}
+
+func TestAcceptsNDJSON(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+	assert.False(t, AcceptsNDJSON(r))
+
+	r.Header.Set("Accept", "application/x-ndjson")
+	assert.True(t, AcceptsNDJSON(r))
+
+	assert.True(t, AcceptsNDJSON(httptest.NewRequest("GET", "/?format=ndjson", nil)))
+}
@@ -0,0 +1,82 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/renanrt/lab-go-api/apperrors"
+	"github.com/renanrt/lab-go-api/model"
+	"github.com/renanrt/lab-go-api/store"
+)
+
+// getTaxes returns the taxes saved by the retailer that are effective at the as_of date, now by default
+func getTaxes(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	asOf, err := parseDate(r.URL.Query().Get("as_of"))
+	if err != nil {
+		RespondWithError(w, r, err)
+		return
+	}
+
+	service := getService(r)
+	taxes, err := service.GetRetailerTaxes(r.Context(), asOf, RequestRetailer(r))
+	if err != nil {
+		RespondWithError(w, r, err)
+		return
+	}
+
+	RespondWithCollection(w, r, taxes, http.StatusOK)
+}
+
+// saveTax creates a tax, or updates it when the id is in the path.
+// The tax always belongs to the retailer of the request.
+func saveTax(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	tax := &model.Tax{}
+	if err := json.NewDecoder(r.Body).Decode(tax); err != nil {
+		RespondWithError(w, r, apperrors.Validation("the body must be a JSON tax", nil))
+		return
+	}
+
+	code := http.StatusCreated
+	tax.ID = ""
+	if id := ps.ByName("id"); id != "" {
+		tax.ID = id
+		code = http.StatusOK
+	}
+
+	service := getService(r)
+	if err := service.SaveRetailerTax(r.Context(), RequestRetailer(r), tax); err != nil {
+		if err == store.ErrNotFound {
+			err = apperrors.NotFound("tax not found")
+		}
+		RespondWithError(w, r, err)
+		return
+	}
+
+	RespondWithData(w, r, tax, code)
+}
+
+// acceptTaxes saves the rates of a tax group accepted by the retailer, and returns the saved taxes.
+// The rates are matched with the saved taxes effective at the as_of date, now by default.
+func acceptTaxes(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	asOf, err := parseDate(r.URL.Query().Get("as_of"))
+	if err != nil {
+		RespondWithError(w, r, err)
+		return
+	}
+
+	taxGroup := &model.TaxGroup{}
+	if err := json.NewDecoder(r.Body).Decode(taxGroup); err != nil {
+		RespondWithError(w, r, apperrors.Validation("the body must be a JSON tax group", nil))
+		return
+	}
+
+	service := getService(r)
+	taxes, err := service.AcceptTaxes(r.Context(), asOf, RequestRetailer(r), taxGroup)
+	if err != nil {
+		RespondWithError(w, r, err)
+		return
+	}
+
+	RespondWithCollection(w, r, taxes, http.StatusOK)
+}
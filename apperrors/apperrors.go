@@ -0,0 +1,130 @@
+// Package apperrors has the errors returned to the api clients. They implement the error
+// interfaces of the api package, so the api responds with their status, code and fields.
+package apperrors
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Error is an error with a code of the catalog. Message is returned to the clients, so it
+// must not have internal details, those go in Cause and Context that are only logged.
+type Error struct {
+	Code    Code
+	Message string
+	// Fields maps the invalid fields of the request to the problem
+	Fields map[string]string
+	// Context has data about the error for the logs
+	Context map[string]interface{}
+	// Cause is the internal error, if any
+	Cause error
+
+	retryAfter time.Duration
+}
+
+// New creates an error with the code of the catalog
+func New(code Code, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
+
+// Validation is returned when the request is not valid. Fields may be nil.
+func Validation(message string, fields map[string]string) *Error {
+	return &Error{Code: CodeInvalidRequest, Message: message, Fields: fields}
+}
+
+// Invalid is a validation error with a specific code, whose message lists the invalid
+// fields, like "invalid address: zipcode is mandatory"
+func Invalid(code Code, what string, fields map[string]string) *Error {
+	problems := make([]string, 0, len(fields))
+	for field, problem := range fields {
+		problems = append(problems, field+" "+problem)
+	}
+	sort.Strings(problems)
+	return &Error{Code: code, Message: "invalid " + what + ": " + strings.Join(problems, ", "), Fields: fields}
+}
+
+// NotFound is returned when the requested resource doesn't exist
+func NotFound(message string) *Error {
+	return New(CodeNotFound, message)
+}
+
+// Conflict is returned when the resource is not in a state that allows the operation
+func Conflict(message string) *Error {
+	return New(CodeConflict, message)
+}
+
+// Unauthorized is returned when the request has no valid credentials
+func Unauthorized(message string) *Error {
+	return New(CodeUnauthorized, message)
+}
+
+// Forbidden is returned when the principal is not allowed to do the operation
+func Forbidden(message string) *Error {
+	return New(CodeForbidden, message)
+}
+
+// RateLimited is returned when a rate limit doesn't allow the request until retryAfter has passed
+func RateLimited(message string, retryAfter time.Duration) *Error {
+	return &Error{Code: CodeRateLimited, Message: message, retryAfter: retryAfter}
+}
+
+// Upstream is returned when a tax provider fails. The cause is only logged, as it may have
+// details of the provider api.
+func Upstream(provider string, cause error) *Error {
+	return &Error{
+		Code:    CodeUpstream,
+		Message: fmt.Sprintf("provider %q failed to find the taxes", provider),
+		Context: map[string]interface{}{"provider": provider},
+		Cause:   cause,
+	}
+}
+
+// RetryAfter returns how long the client should wait before retrying, or 0 if it's not known
+func (e *Error) RetryAfter() time.Duration {
+	return e.retryAfter
+}
+
+// WithContext returns a copy of the error with a value added to its context
+func (e *Error) WithContext(key string, value interface{}) *Error {
+	c := *e
+	c.Context = make(map[string]interface{}, len(e.Context)+1)
+	for k, v := range e.Context {
+		c.Context[k] = v
+	}
+	c.Context[key] = value
+	return &c
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// StatusCode returns the http status code of the error code
+func (e *Error) StatusCode() int {
+	return e.Code.Status()
+}
+
+// ErrorCode returns the numeric code of the catalog
+func (e *Error) ErrorCode() int {
+	return int(e.Code)
+}
+
+// ErrorFields returns the invalid fields
+func (e *Error) ErrorFields() map[string]string {
+	return e.Fields
+}
+
+// LoggableError returns the message with the cause
+func (e *Error) LoggableError() string {
+	if e.Cause == nil {
+		return e.Message
+	}
+	return e.Message + ": " + e.Cause.Error()
+}
+
+// ErrorContext returns the context of the error
+func (e *Error) ErrorContext() map[string]interface{} {
+	return e.Context
+}
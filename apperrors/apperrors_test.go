@@ -0,0 +1,57 @@
+package apperrors
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConstructors(t *testing.T) {
+	tests := []struct {
+		err    *Error
+		code   Code
+		status int
+	}{
+		{Validation("invalid body", nil), CodeInvalidRequest, http.StatusBadRequest},
+		{NotFound("job not found"), CodeNotFound, http.StatusNotFound},
+		{Conflict("the API key is revoked"), CodeConflict, http.StatusConflict},
+		{Unauthorized("invalid credentials"), CodeUnauthorized, http.StatusUnauthorized},
+		{Forbidden("not allowed"), CodeForbidden, http.StatusForbidden},
+		{RateLimited("too many requests", time.Second), CodeRateLimited, http.StatusTooManyRequests},
+		{Upstream("avalara", errors.New("timeout")), CodeUpstream, http.StatusBadGateway},
+		{New(CodeNoTaxesFound, "no taxes found"), CodeNoTaxesFound, http.StatusNotFound},
+		{New(CodeRequestTooLarge, "the body is too large"), CodeRequestTooLarge, http.StatusRequestEntityTooLarge},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.code, test.err.Code, test.err.Error())
+		assert.Equal(t, int(test.code), test.err.ErrorCode(), test.err.Error())
+		assert.Equal(t, test.status, test.err.StatusCode(), test.err.Error())
+	}
+
+	assert.Equal(t, http.StatusInternalServerError, Code(9999).Status())
+}
+
+func TestInvalid(t *testing.T) {
+	err := Invalid(CodeInvalidAddress, "address", map[string]string{"zipcode": "is mandatory", "country": "is not supported"})
+	assert.Equal(t, "invalid address: country is not supported, zipcode is mandatory", err.Error())
+	assert.Equal(t, map[string]string{"zipcode": "is mandatory", "country": "is not supported"}, err.ErrorFields())
+	assert.Equal(t, http.StatusBadRequest, err.StatusCode())
+}
+
+// tests that the cause is only in the loggable error
+func TestUpstream(t *testing.T) {
+	err := Upstream("avalara", errors.New("POST https://avalara/v2/taxes: 503"))
+	assert.Equal(t, `provider "avalara" failed to find the taxes`, err.Error())
+	assert.Equal(t, `provider "avalara" failed to find the taxes: POST https://avalara/v2/taxes: 503`, err.LoggableError())
+	assert.Equal(t, map[string]interface{}{"provider": "avalara"}, err.ErrorContext())
+}
+
+func TestWithContext(t *testing.T) {
+	err := NotFound("job not found")
+	withJob := err.WithContext("job_id", "job-1")
+	assert.Equal(t, map[string]interface{}{"job_id": "job-1"}, withJob.ErrorContext())
+	assert.Nil(t, err.ErrorContext())
+}
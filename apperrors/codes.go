@@ -0,0 +1,82 @@
+package apperrors
+
+import "net/http"
+
+// Code is the numeric error code returned to the clients. Codes are stable, clients may
+// rely on them, so existing codes must never change their meaning or be reused.
+type Code int
+
+// The catalog of error codes. The first digit groups the codes by kind of error.
+const (
+	// CodeInvalidRequest is a malformed body or parameter
+	CodeInvalidRequest Code = 1000
+	// CodeInvalidAddress is an address that is incomplete or doesn't exist in the country
+	CodeInvalidAddress Code = 1001
+	// CodeInvalidCoordinates is a latitude or longitude out of range
+	CodeInvalidCoordinates Code = 1002
+	// CodeUnsupportedCountry is a country without tax support
+	CodeUnsupportedCountry Code = 1003
+	// CodeUnknownProvider is a tax provider that doesn't exist
+	CodeUnknownProvider Code = 1004
+	// CodeRequestTooLarge is a body over the size limit of the endpoint
+	CodeRequestTooLarge Code = 1005
+
+	// CodeUnauthorized is a request without valid credentials
+	CodeUnauthorized Code = 2000
+	// CodeForbidden is an operation the principal is not allowed to do
+	CodeForbidden Code = 2001
+
+	// CodeNotFound is a resource that doesn't exist
+	CodeNotFound Code = 3000
+	// CodeNoTaxesFound is an address or point without known taxes
+	CodeNoTaxesFound Code = 3001
+
+	// CodeConflict is an operation that is not possible in the current state of the resource
+	CodeConflict Code = 4000
+	// CodeAmbiguousAddress is an address that matches more than one jurisdiction
+	CodeAmbiguousAddress Code = 4001
+
+	// CodeRateLimited is a request rejected by a rate limit
+	CodeRateLimited Code = 5000
+
+	// CodeUpstream is a failure of a tax provider
+	CodeUpstream Code = 6000
+)
+
+type codeInfo struct {
+	status int
+	title  string
+}
+
+var catalog = map[Code]codeInfo{
+	CodeInvalidRequest:     {http.StatusBadRequest, "Invalid request"},
+	CodeInvalidAddress:     {http.StatusBadRequest, "Invalid address"},
+	CodeInvalidCoordinates: {http.StatusBadRequest, "Invalid coordinates"},
+	CodeUnsupportedCountry: {http.StatusBadRequest, "Unsupported country"},
+	CodeUnknownProvider:    {http.StatusBadRequest, "Unknown provider"},
+	CodeRequestTooLarge:    {http.StatusRequestEntityTooLarge, "Request too large"},
+	CodeUnauthorized:       {http.StatusUnauthorized, "Unauthorized"},
+	CodeForbidden:          {http.StatusForbidden, "Forbidden"},
+	CodeNotFound:           {http.StatusNotFound, "Not found"},
+	CodeNoTaxesFound:       {http.StatusNotFound, "No taxes found"},
+	CodeConflict:           {http.StatusConflict, "Conflict"},
+	CodeAmbiguousAddress:   {http.StatusConflict, "Ambiguous address"},
+	CodeRateLimited:        {http.StatusTooManyRequests, "Rate limited"},
+	CodeUpstream:           {http.StatusBadGateway, "Provider error"},
+}
+
+// Status returns the http status code of the code, or 500 for unknown codes
+func (c Code) Status() int {
+	if info, ok := catalog[c]; ok {
+		return info.status
+	}
+	return http.StatusInternalServerError
+}
+
+// Title returns a short summary of the code, that is the same for every error with it
+func (c Code) Title() string {
+	if info, ok := catalog[c]; ok {
+		return info.title
+	}
+	return http.StatusText(http.StatusInternalServerError)
+}
@@ -0,0 +1,85 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+
+	"github.com/renanrt/lab-go-api/model"
+)
+
+// APIKeyHeader is the header used to send API keys
+const APIKeyHeader = "X-Api-Key"
+
+// StaticKeyAuthenticator authenticates API keys from a fixed list, usually from configuration.
+// Keys are kept hashed, so they don't stay in memory in plain text.
+type StaticKeyAuthenticator struct {
+	principals map[string]*Principal
+}
+
+// NewStaticKeyAuthenticator creates an authenticator with the principals by API key
+func NewStaticKeyAuthenticator(keys map[string]*Principal) *StaticKeyAuthenticator {
+	a := &StaticKeyAuthenticator{principals: map[string]*Principal{}}
+	for key, p := range keys {
+		a.principals[HashKey(key)] = p
+	}
+	return a
+}
+
+// Authenticate finds the principal of the API key header
+func (a *StaticKeyAuthenticator) Authenticate(r *http.Request) (*Principal, error) {
+	key := strings.TrimSpace(r.Header.Get(APIKeyHeader))
+	if key == "" {
+		return nil, ErrNoCredentials
+	}
+
+	p, ok := a.principals[HashKey(key)]
+	if !ok {
+		return nil, ErrInvalidCredentials
+	}
+	principal := *p
+	return &principal, nil
+}
+
+// KeyLookup finds the API keys managed by the retailers
+type KeyLookup interface {
+	// LookupAPIKey returns the key, or nil if it's unknown or revoked
+	LookupAPIKey(ctx context.Context, key string) (*model.APIKey, error)
+}
+
+// StoredKeyAuthenticator authenticates the API keys created by the retailers.
+// The principal is restricted to the scopes of the key.
+type StoredKeyAuthenticator struct {
+	keys KeyLookup
+}
+
+// NewStoredKeyAuthenticator creates an authenticator that finds the keys with the lookup
+func NewStoredKeyAuthenticator(keys KeyLookup) *StoredKeyAuthenticator {
+	return &StoredKeyAuthenticator{keys: keys}
+}
+
+// Authenticate finds the key of the API key header
+func (a *StoredKeyAuthenticator) Authenticate(r *http.Request) (*Principal, error) {
+	secret := strings.TrimSpace(r.Header.Get(APIKeyHeader))
+	if secret == "" {
+		return nil, ErrNoCredentials
+	}
+
+	key, err := a.keys.LookupAPIKey(r.Context(), secret)
+	if err != nil {
+		return nil, err
+	}
+	if key == nil {
+		return nil, ErrInvalidCredentials
+	}
+	scopes := append([]model.Scope{}, key.Scopes...)
+	return &Principal{Subject: "api-key:" + key.ID, RetailerID: key.RetailerID, Scopes: scopes}, nil
+}
+
+// HashKey returns the SHA-256 hash of an API key, in hex
+func HashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
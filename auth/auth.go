@@ -0,0 +1,74 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/renanrt/lab-go-api/model"
+)
+
+// Principal is the authenticated caller of a request
+type Principal struct {
+	// Subject identifies who is calling, like a user or an integration
+	Subject string `json:"sub"`
+	// RetailerID is the retailer the caller acts on behalf of. Staff may have none
+	RetailerID string `json:"retailer_id,omitempty"`
+	// Role is the role of the caller, retailer if empty
+	Role Role `json:"role,omitempty"`
+	// Impersonator is the staff member impersonating the retailer, if any
+	Impersonator string `json:"impersonator,omitempty"`
+	// Scopes restricts what the caller can do, nil means there are no restrictions
+	Scopes []model.Scope `json:"scopes,omitempty"`
+}
+
+// Authenticator resolves the principal of a request from its credentials
+type Authenticator interface {
+	// Authenticate returns ErrNoCredentials when the request has no credentials it understands,
+	// and ErrInvalidCredentials when they are wrong, expired, or unknown.
+	Authenticate(r *http.Request) (*Principal, error)
+}
+
+var (
+	// ErrNoCredentials is returned when the request has no credentials
+	ErrNoCredentials = errors.New("no credentials")
+	// ErrInvalidCredentials is returned when the credentials are not valid
+	ErrInvalidCredentials = errors.New("invalid credentials")
+)
+
+// Chain tries each authenticator in order, until one accepts the credentials in the request.
+// Authenticators may share the same credentials, like static and stored API keys.
+type Chain []Authenticator
+
+// Authenticate returns the principal of the first authenticator that accepts the credentials
+func (c Chain) Authenticate(r *http.Request) (*Principal, error) {
+	result := ErrNoCredentials
+	for _, a := range c {
+		p, err := a.Authenticate(r)
+		switch err {
+		case nil:
+			return p, nil
+		case ErrNoCredentials:
+		case ErrInvalidCredentials:
+			result = err
+		default:
+			return nil, err
+		}
+	}
+	return nil, result
+}
+
+type principalKeyType int
+
+const principalKey principalKeyType = iota
+
+// NewContext returns a context with the principal
+func NewContext(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey, p)
+}
+
+// FromContext returns the principal of the context, if it was authenticated
+func FromContext(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(principalKey).(*Principal)
+	return p, ok
+}
@@ -0,0 +1,209 @@
+package auth
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/renanrt/lab-go-api/model"
+	"github.com/stretchr/testify/assert"
+)
+
+var testKeys = map[string][]byte{"2018-01": []byte("secret"), "2018-07": []byte("new-secret")}
+
+func TestJWTAuthenticator(t *testing.T) {
+	a := NewJWTAuthenticator(testKeys)
+	a.now = func() time.Time { return time.Unix(1500000000, 0) }
+
+	token, err := SignJWT("2018-07", testKeys["2018-07"], &Claims{
+		Principal: Principal{Subject: "user-1", RetailerID: "retailer-1"},
+		ExpiresAt: 1500000600,
+	})
+	assert.NoError(t, err)
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Authorization", "Bearer "+token)
+	p, err := a.Authenticate(r)
+	assert.NoError(t, err)
+	assert.Equal(t, "retailer-1", p.RetailerID)
+	assert.Equal(t, "user-1", p.Subject)
+
+	// expired, after the leeway
+	a.now = func() time.Time { return time.Unix(1500000700, 0) }
+	_, err = a.Authenticate(r)
+	assert.Equal(t, ErrInvalidCredentials, err)
+
+	_, err = a.Authenticate(httptest.NewRequest("GET", "/", nil))
+	assert.Equal(t, ErrNoCredentials, err)
+}
+
+func TestJWTVerifyInvalid(t *testing.T) {
+	a := NewJWTAuthenticator(testKeys)
+	a.now = func() time.Time { return time.Unix(1500000000, 0) }
+	claims := &Claims{Principal: Principal{RetailerID: "retailer-1"}, ExpiresAt: 1500000600}
+
+	// signed with the wrong key
+	token, _ := SignJWT("2018-01", []byte("another-secret"), claims)
+	_, err := a.Verify(token)
+	assert.Error(t, err)
+
+	token, _ = SignJWT("unknown", testKeys["2018-01"], claims)
+	_, err = a.Verify(token)
+	assert.Error(t, err)
+
+	token, _ = SignJWT("2018-01", testKeys["2018-01"], &Claims{ExpiresAt: 1500000600})
+	_, err = a.Verify(token)
+	assert.Error(t, err)
+
+	// tokens that never expire
+	token, _ = SignJWT("2018-01", testKeys["2018-01"], &Claims{Principal: Principal{RetailerID: "retailer-1"}})
+	_, err = a.Verify(token)
+	assert.EqualError(t, err, "token has no expiration")
+
+	// staff tokens don't need a retailer
+	token, _ = SignJWT("2018-01", testKeys["2018-01"], &Claims{Principal: Principal{Subject: "admin-1", Role: RoleAdmin}, ExpiresAt: 1500000600})
+	_, err = a.Verify(token)
+	assert.NoError(t, err)
+
+	_, err = a.Verify("not-a-token")
+	assert.Error(t, err)
+}
+
+func TestStaticKeyAuthenticator(t *testing.T) {
+	a := NewStaticKeyAuthenticator(map[string]*Principal{"key-1": {Subject: "partner", RetailerID: "retailer-1"}})
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set(APIKeyHeader, "key-1")
+	p, err := a.Authenticate(r)
+	assert.NoError(t, err)
+	assert.Equal(t, "retailer-1", p.RetailerID)
+
+	r.Header.Set(APIKeyHeader, "key-2")
+	_, err = a.Authenticate(r)
+	assert.Equal(t, ErrInvalidCredentials, err)
+}
+
+type keyLookup map[string]*model.APIKey
+
+func (l keyLookup) LookupAPIKey(ctx context.Context, key string) (*model.APIKey, error) {
+	return l[key], nil
+}
+
+func TestStoredKeyAuthenticator(t *testing.T) {
+	a := NewStoredKeyAuthenticator(keyLookup{"lgk_1": {ID: "key-1", RetailerID: "retailer-1", Scopes: []model.Scope{model.ScopeSearch}}})
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set(APIKeyHeader, "lgk_1")
+	p, err := a.Authenticate(r)
+	assert.NoError(t, err)
+	assert.Equal(t, "retailer-1", p.RetailerID)
+	assert.True(t, p.HasScope(model.ScopeSearch))
+	assert.False(t, p.HasScope(model.ScopeTaxesWrite))
+
+	r.Header.Set(APIKeyHeader, "lgk_2")
+	_, err = a.Authenticate(r)
+	assert.Equal(t, ErrInvalidCredentials, err)
+}
+
+func TestChain(t *testing.T) {
+	c := Chain{NewJWTAuthenticator(testKeys), NewStaticKeyAuthenticator(map[string]*Principal{"key-1": {RetailerID: "retailer-1"}})}
+
+	r := httptest.NewRequest("GET", "/", nil)
+	_, err := c.Authenticate(r)
+	assert.Equal(t, ErrNoCredentials, err)
+
+	r.Header.Set(APIKeyHeader, "key-1")
+	p, err := c.Authenticate(r)
+	assert.NoError(t, err)
+	assert.Equal(t, "retailer-1", p.RetailerID)
+
+	r.Header.Del(APIKeyHeader)
+	r.Header.Set("Authorization", "Bearer invalid")
+	_, err = c.Authenticate(r)
+	assert.Equal(t, ErrInvalidCredentials, err)
+
+	// the API keys of the retailers are tried after the static ones
+	c = append(c, NewStoredKeyAuthenticator(keyLookup{"lgk_1": {ID: "key-1", RetailerID: "retailer-2"}}))
+	r = httptest.NewRequest("GET", "/", nil)
+	r.Header.Set(APIKeyHeader, "lgk_1")
+	p, err = c.Authenticate(r)
+	assert.NoError(t, err)
+	assert.Equal(t, "retailer-2", p.RetailerID)
+}
+
+func TestContext(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+	_, ok := FromContext(r.Context())
+	assert.False(t, ok)
+
+	ctx := NewContext(r.Context(), &Principal{RetailerID: "retailer-1"})
+	p, ok := FromContext(ctx)
+	assert.True(t, ok)
+	assert.Equal(t, "retailer-1", p.RetailerID)
+}
+
+func TestParseKeys(t *testing.T) {
+	jwtKeys, err := ParseJWTKeys("2018-01:secret, 2018-07:new:secret,fallback")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]byte{"2018-01": []byte("secret"), "2018-07": []byte("new:secret"), "": []byte("fallback")}, jwtKeys)
+
+	_, err = ParseJWTKeys("2018-01:")
+	assert.Error(t, err)
+
+	apiKeys, err := ParseAPIKeys("key-1:retailer-1,key-2:retailer-2")
+	assert.NoError(t, err)
+	assert.Len(t, apiKeys, 2)
+	assert.Equal(t, "retailer-2", apiKeys["key-2"].RetailerID)
+
+	apiKeys, err = ParseAPIKeys("key-3::admin")
+	assert.NoError(t, err)
+	assert.Equal(t, RoleAdmin, apiKeys["key-3"].Role)
+
+	for _, invalid := range []string{"key-1", "key-1:", "key-1:retailer-1:owner"} {
+		_, err = ParseAPIKeys(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestRoles(t *testing.T) {
+	retailer := &Principal{Subject: "user-1", RetailerID: "retailer-1"}
+	support := &Principal{Subject: "support-1", Role: RoleSupport}
+	admin := &Principal{Subject: "admin-1", Role: RoleAdmin}
+
+	assert.True(t, RetailerAccess(retailer))
+	assert.False(t, RetailerAccess(support))
+	assert.False(t, RequireRole(RoleAdmin)(retailer))
+	assert.False(t, RequireRole(RoleAdmin)(support))
+	assert.True(t, RequireRole(RoleAdmin)(admin))
+	assert.True(t, RequireRole(RoleSupport)(admin))
+
+	_, err := retailer.Impersonate("retailer-2")
+	assert.Equal(t, ErrImpersonationDenied, err)
+
+	impersonated, err := support.Impersonate("retailer-2")
+	assert.NoError(t, err)
+	assert.Equal(t, "retailer-2", impersonated.RetailerID)
+	assert.Equal(t, "support-1", impersonated.Impersonator)
+	assert.True(t, RetailerAccess(impersonated))
+	assert.Empty(t, support.RetailerID)
+
+	// support staff impersonating a retailer can only read, admins can also write
+	assert.True(t, RetailerWriteAccess(retailer))
+	assert.False(t, RetailerWriteAccess(impersonated))
+	impersonated, err = admin.Impersonate("retailer-2")
+	assert.NoError(t, err)
+	assert.True(t, RetailerWriteAccess(impersonated))
+	assert.False(t, RetailerWriteAccess(admin))
+}
+
+func TestScopes(t *testing.T) {
+	user := &Principal{RetailerID: "retailer-1"}
+	key := &Principal{RetailerID: "retailer-1", Scopes: []model.Scope{model.ScopeSearch}}
+
+	write := All(RetailerAccess, RequireScope(model.ScopeTaxesWrite))
+	assert.True(t, write(user))
+	assert.False(t, write(key))
+	assert.True(t, All(RetailerAccess, RequireScope(model.ScopeSearch))(key))
+	assert.False(t, write(&Principal{Role: RoleAdmin}))
+}
@@ -0,0 +1,149 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// JWTAuthenticator authenticates HMAC-SHA256 signed JWTs sent as bearer tokens.
+// Keys are selected by the "kid" header, so they can be rotated without invalidating
+// the tokens signed with the previous key.
+type JWTAuthenticator struct {
+	// Keys maps the key ids to their secrets. The empty id is used by tokens without "kid"
+	Keys map[string][]byte
+	// Leeway is the clock skew tolerated when checking the expiration
+	Leeway time.Duration
+
+	// now is replaced in tests
+	now func() time.Time
+}
+
+// Claims are the JWT claims used to authenticate. Tokens without an expiration are rejected.
+type Claims struct {
+	Principal
+	ExpiresAt int64 `json:"exp,omitempty"`
+	NotBefore int64 `json:"nbf,omitempty"`
+	IssuedAt  int64 `json:"iat,omitempty"`
+}
+
+type jwtHeader struct {
+	Algorithm string `json:"alg"`
+	Type      string `json:"typ,omitempty"`
+	KeyID     string `json:"kid,omitempty"`
+}
+
+// NewJWTAuthenticator creates an authenticator with the keys
+func NewJWTAuthenticator(keys map[string][]byte) *JWTAuthenticator {
+	return &JWTAuthenticator{Keys: keys, Leeway: time.Minute}
+}
+
+// Authenticate validates the bearer token of the Authorization header
+func (a *JWTAuthenticator) Authenticate(r *http.Request) (*Principal, error) {
+	authorization := r.Header.Get("Authorization")
+	if !strings.HasPrefix(authorization, "Bearer ") {
+		return nil, ErrNoCredentials
+	}
+
+	claims, err := a.Verify(strings.TrimSpace(strings.TrimPrefix(authorization, "Bearer ")))
+	if err != nil {
+		return nil, ErrInvalidCredentials
+	}
+	return &claims.Principal, nil
+}
+
+// Verify checks the signature and the time claims of a token
+func (a *JWTAuthenticator) Verify(token string) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("token must have 3 parts")
+	}
+
+	header := jwtHeader{}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, err
+	}
+	if header.Algorithm != "HS256" {
+		return nil, errors.New("only HS256 tokens are supported")
+	}
+	key, ok := a.Keys[header.KeyID]
+	if !ok {
+		return nil, errors.New("unknown key id")
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, err
+	}
+	if !hmac.Equal(signature, sign(key, parts[0]+"."+parts[1])) {
+		return nil, errors.New("invalid signature")
+	}
+
+	claims := &Claims{}
+	if err := decodeSegment(parts[1], claims); err != nil {
+		return nil, err
+	}
+
+	now := a.clock()
+	if claims.ExpiresAt == 0 {
+		return nil, errors.New("token has no expiration")
+	}
+	if now.After(time.Unix(claims.ExpiresAt, 0).Add(a.Leeway)) {
+		return nil, errors.New("token expired")
+	}
+	if claims.NotBefore != 0 && now.Before(time.Unix(claims.NotBefore, 0).Add(-a.Leeway)) {
+		return nil, errors.New("token not valid yet")
+	}
+	if claims.RetailerID == "" && claims.GetRole() == RoleRetailer {
+		return nil, errors.New("token has no retailer")
+	}
+	return claims, nil
+}
+
+// SignJWT creates a HS256 token with the claims, signed by the key with the key id
+func SignJWT(keyID string, key []byte, claims *Claims) (string, error) {
+	header, err := encodeSegment(jwtHeader{Algorithm: "HS256", Type: "JWT", KeyID: keyID})
+	if err != nil {
+		return "", err
+	}
+	payload, err := encodeSegment(claims)
+	if err != nil {
+		return "", err
+	}
+	signed := header + "." + payload
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sign(key, signed)), nil
+}
+
+func (a *JWTAuthenticator) clock() time.Time {
+	if a.now == nil {
+		return time.Now()
+	}
+	return a.now()
+}
+
+func sign(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func encodeSegment(v interface{}) (string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
@@ -0,0 +1,60 @@
+package auth
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ParseJWTKeys parses a list of JWT keys in the format "kid1:secret1,kid2:secret2".
+// A key without id is used for the tokens without "kid".
+func ParseJWTKeys(value string) (map[string][]byte, error) {
+	keys := map[string][]byte{}
+	for _, pair := range splitList(value) {
+		kid, secret := "", pair
+		if i := strings.Index(pair, ":"); i >= 0 {
+			kid, secret = pair[:i], pair[i+1:]
+		}
+		if secret == "" {
+			return nil, fmt.Errorf("JWT key %q has no secret", kid)
+		}
+		keys[kid] = []byte(secret)
+	}
+	return keys, nil
+}
+
+// ParseAPIKeys parses a list of static API keys in the format "key1:retailer1,key2:retailer2".
+// An optional role can follow the retailer, staff keys may have no retailer: "key3::admin"
+func ParseAPIKeys(value string) (map[string]*Principal, error) {
+	keys := map[string]*Principal{}
+	for _, item := range splitList(value) {
+		parts := strings.Split(item, ":")
+		if len(parts) < 2 || len(parts) > 3 || parts[0] == "" {
+			return nil, fmt.Errorf("API keys must be in the format key:retailer[:role]")
+		}
+		p := &Principal{Subject: "api-key", RetailerID: parts[1]}
+		if len(parts) == 3 {
+			p.Role = Role(parts[2])
+		}
+		switch p.GetRole() {
+		case RoleRetailer:
+			if p.RetailerID == "" {
+				return nil, fmt.Errorf("retailer API keys must have a retailer")
+			}
+		case RoleSupport, RoleAdmin:
+		default:
+			return nil, fmt.Errorf("unknown role %q", p.Role)
+		}
+		keys[parts[0]] = p
+	}
+	return keys, nil
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,109 @@
+package auth
+
+import (
+	"errors"
+
+	"github.com/renanrt/lab-go-api/model"
+)
+
+// Role defines what a principal is allowed to do
+type Role string
+
+const (
+	// RoleRetailer can only use its own taxes, jobs and lookups
+	RoleRetailer Role = "retailer"
+	// RoleSupport can impersonate any retailer to debug their requests
+	RoleSupport Role = "support"
+	// RoleAdmin can do everything, including the admin operations like purging caches
+	RoleAdmin Role = "admin"
+)
+
+// ErrImpersonationDenied is returned when a principal without a staff role tries to impersonate a retailer
+var ErrImpersonationDenied = errors.New("only support and admin staff can impersonate retailers")
+
+// Policy decides if a principal can use a route
+type Policy func(p *Principal) bool
+
+// RetailerAccess allows principals acting for a retailer: retailers themselves, and staff impersonating one
+func RetailerAccess(p *Principal) bool {
+	return p.RetailerID != ""
+}
+
+// RetailerWriteAccess allows principals changing the data of a retailer: retailers themselves,
+// and admins impersonating one. Support staff impersonating a retailer can only read.
+func RetailerWriteAccess(p *Principal) bool {
+	return RetailerAccess(p) && (p.Impersonator == "" || p.GetRole() == RoleAdmin)
+}
+
+// RequireRole allows principals with one of the roles. Admins are always allowed
+func RequireRole(roles ...Role) Policy {
+	return func(p *Principal) bool {
+		return p.HasRole(roles...)
+	}
+}
+
+// RequireScope allows principals whose scopes include the scope
+func RequireScope(scope model.Scope) Policy {
+	return func(p *Principal) bool {
+		return p.HasScope(scope)
+	}
+}
+
+// All allows principals allowed by all the policies
+func All(policies ...Policy) Policy {
+	return func(p *Principal) bool {
+		for _, policy := range policies {
+			if !policy(p) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// HasScope checks if the principal can use the scope. Principals without scopes have no restrictions
+func (p *Principal) HasScope(scope model.Scope) bool {
+	if p.Scopes == nil {
+		return true
+	}
+	for _, s := range p.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// GetRole returns the role of the principal. Principals without role are retailers
+func (p *Principal) GetRole() Role {
+	if p.Role == "" {
+		return RoleRetailer
+	}
+	return p.Role
+}
+
+// HasRole checks if the principal has one of the roles. Admins have all roles
+func (p *Principal) HasRole(roles ...Role) bool {
+	role := p.GetRole()
+	if role == RoleAdmin {
+		return true
+	}
+	for _, r := range roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// Impersonate returns a copy of the principal acting for the retailer.
+// The staff member stays recorded as the impersonator.
+func (p *Principal) Impersonate(retailerID string) (*Principal, error) {
+	if !p.HasRole(RoleSupport) {
+		return nil, ErrImpersonationDenied
+	}
+	impersonated := *p
+	impersonated.RetailerID = retailerID
+	impersonated.Impersonator = p.Subject
+	return &impersonated, nil
+}
@@ -0,0 +1,351 @@
+// Package config loads the configuration of the application. The values are merged from
+// the defaults, an env file, the environment variables and the command line flags, each
+// one overriding the previous ones.
+package config
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/renanrt/lab-go-api/logging"
+)
+
+// The environments the application runs in, set with ENV
+const (
+	EnvDevelopment = "development"
+	EnvTest        = "test"
+	EnvProduction  = "production"
+)
+
+// DefaultEnvFile has the development values. It's only read when ENV is development, unless
+// another file is chosen with ENV_FILE or -env-file.
+const DefaultEnvFile = "etc/.env.default"
+
+// LocalEnvFile overrides DefaultEnvFile in development. It's not committed, so it's where the
+// development credentials go, like the ones of etc/.env.example.
+const LocalEnvFile = "etc/.env"
+
+// Config is the configuration of the application
+type Config struct {
+	// Env is the environment, like "production"
+	Env string
+	// Port where the API listens
+	Port      int
+	LogLevel  logging.Level
+	LogFormat logging.Format
+	Auth      Auth
+	Service   Service
+}
+
+// Auth is the configuration of the authentication of the API
+type Auth struct {
+	// JWTKeys are the keys of the JWT tokens, in the format "kid1:secret1,kid2:secret2"
+	JWTKeys string
+	// APIKeys are the static API keys, in the format "key1:retailer1[:role],key2:retailer2[:role]"
+	APIKeys string
+}
+
+// Service is the configuration of the tax service
+type Service struct {
+	// StoreFile is where the retailer data is saved. The data is kept in memory when it's empty
+	StoreFile string
+	// RateChangesFile is a JSON file with the announced rate changes. When it's empty, the
+	// changes known by the providers are used
+	RateChangesFile string
+	// BoundaryFile is a GeoJSON file with the jurisdiction boundaries, used to search by
+	// coordinates with the providers that don't support them
+	BoundaryFile string
+	// CacheTTL is how long the lookups of the providers are cached, 0 disables the cache
+	CacheTTL time.Duration
+	// CacheSize is the max number of lookups cached, 0 is unlimited
+	CacheSize int
+	// ProviderRateLimit is the max number of requests per second to a provider, 0 is unlimited
+	ProviderRateLimit int
+	// BatchParallelism is the max number of concurrent lookups of a batch, 0 is the service default
+	BatchParallelism int
+}
+
+// Addr is the address where the API listens, like ":8080"
+func (c Config) Addr() string {
+	return ":" + strconv.Itoa(c.Port)
+}
+
+// defaults are the values used when they are not set anywhere else. The environment is
+// production unless it's chosen, so a deploy without ENV gets the strictest validation.
+var defaults = map[string]string{
+	"ENV":        EnvProduction,
+	"PORT":       "8080",
+	"LOG_LEVEL":  "info",
+	"LOG_FORMAT": "human",
+	"CACHE_TTL":  "1h",
+	"CACHE_SIZE": "10000",
+}
+
+// required are the values that must be set in each environment
+var required = map[string][]string{
+	// The memory store loses the jobs and the API keys of the retailers on every restart
+	EnvProduction: {"AUTH_JWT_KEYS", "STORE_FILE"},
+}
+
+// flags are the command line flags and the variables they set
+var flags = []struct {
+	name, key, usage string
+}{
+	{"env", "ENV", "environment: development, test or production (the default)"},
+	{"env-file", "ENV_FILE", "env file with the variables, " + DefaultEnvFile + " by default in development"},
+	{"port", "PORT", "port where the API listens"},
+	{"log-level", "LOG_LEVEL", "log level: debug, info, warn or error"},
+	{"log-format", "LOG_FORMAT", "log format: human or json"},
+	{"store-file", "STORE_FILE", "file where the retailer data is saved"},
+	{"rate-changes-file", "RATE_CHANGES_FILE", "JSON file with the announced rate changes"},
+	{"boundary-file", "BOUNDARY_FILE", "GeoJSON file with the jurisdiction boundaries, for the searches by coordinates"},
+}
+
+// Load loads the configuration with the environment of the process and the flags in args
+func Load(args []string) (Config, error) {
+	return load(args, os.Environ())
+}
+
+func load(args, environ []string) (Config, error) {
+	flagValues, err := parseFlags(args)
+	if err != nil {
+		return Config{}, err
+	}
+	envValues := parseEnviron(environ)
+
+	// The env file is chosen before it's read, so it can't change the environment or itself
+	env := lookup("ENV", flagValues, envValues, defaults)
+	path := lookup("ENV_FILE", flagValues, envValues)
+	var fileValues map[string]string
+	if path != "" {
+		if fileValues, err = ReadEnvFile(path); err != nil {
+			return Config{}, err
+		}
+	} else if env == EnvDevelopment {
+		fileValues = map[string]string{}
+		for _, path := range []string{DefaultEnvFile, LocalEnvFile} {
+			values, err := ReadEnvFile(path)
+			if err != nil && !os.IsNotExist(err) {
+				return Config{}, err
+			}
+			for key, value := range values {
+				fileValues[key] = value
+			}
+		}
+	}
+
+	values := map[string]string{}
+	for _, layer := range []map[string]string{defaults, fileValues, envValues, flagValues} {
+		for key, value := range layer {
+			values[key] = value
+		}
+	}
+	values["ENV"] = env
+	return parse(values)
+}
+
+// lookup returns the value of the key in the first layer that has it
+func lookup(key string, layers ...map[string]string) string {
+	for _, layer := range layers {
+		if value, ok := layer[key]; ok {
+			return value
+		}
+	}
+	return ""
+}
+
+// parseFlags returns the values of the flags that are set in args
+func parseFlags(args []string) (map[string]string, error) {
+	fs := flag.NewFlagSet("lab-go-api", flag.ContinueOnError)
+	keys := map[string]string{}
+	for _, f := range flags {
+		fs.String(f.name, "", f.usage)
+		keys[f.name] = f.key
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if fs.NArg() > 0 {
+		return nil, fmt.Errorf("unexpected argument %q", fs.Arg(0))
+	}
+
+	values := map[string]string{}
+	fs.Visit(func(f *flag.Flag) {
+		values[keys[f.Name]] = f.Value.String()
+	})
+	return values, nil
+}
+
+// parseEnviron parses the KEY=value list of os.Environ
+func parseEnviron(environ []string) map[string]string {
+	values := map[string]string{}
+	for _, kv := range environ {
+		if i := strings.Index(kv, "="); i > 0 {
+			values[kv[:i]] = kv[i+1:]
+		}
+	}
+	return values
+}
+
+// ReadEnvFile reads a file with a KEY=value variable per line. Blank lines and the lines
+// starting with # are skipped, and the values may be quoted.
+func ReadEnvFile(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	values := map[string]string{}
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		i := strings.Index(line, "=")
+		if i <= 0 {
+			return nil, fmt.Errorf("%s:%d: expected KEY=value", path, n)
+		}
+		key, value := strings.TrimSpace(line[:i]), strings.TrimSpace(line[i+1:])
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		values[key] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// parse creates the typed configuration from the merged values, and validates it
+func parse(values map[string]string) (Config, error) {
+	p := &parser{values: values}
+	c := Config{
+		Env:       values["ENV"],
+		Port:      p.int("PORT"),
+		LogLevel:  p.logLevel("LOG_LEVEL"),
+		LogFormat: p.logFormat("LOG_FORMAT"),
+		Auth: Auth{
+			JWTKeys: values["AUTH_JWT_KEYS"],
+			APIKeys: values["AUTH_API_KEYS"],
+		},
+		Service: Service{
+			StoreFile:         values["STORE_FILE"],
+			RateChangesFile:   values["RATE_CHANGES_FILE"],
+			BoundaryFile:      values["BOUNDARY_FILE"],
+			CacheTTL:          p.duration("CACHE_TTL"),
+			CacheSize:         p.int("CACHE_SIZE"),
+			ProviderRateLimit: p.int("PROVIDER_RATE_LIMIT"),
+			BatchParallelism:  p.int("BATCH_PARALLELISM"),
+		},
+	}
+
+	switch c.Env {
+	case EnvDevelopment, EnvTest, EnvProduction:
+	default:
+		p.fail("ENV", "must be development, test or production")
+	}
+	if c.Port <= 0 || c.Port > 65535 {
+		p.fail("PORT", "must be between 1 and 65535")
+	}
+	if c.Service.CacheSize < 0 {
+		p.fail("CACHE_SIZE", "can't be negative")
+	}
+	if c.Service.ProviderRateLimit < 0 {
+		p.fail("PROVIDER_RATE_LIMIT", "can't be negative")
+	}
+	if c.Service.BatchParallelism < 0 {
+		p.fail("BATCH_PARALLELISM", "can't be negative")
+	}
+	for _, key := range required[c.Env] {
+		if strings.TrimSpace(values[key]) == "" {
+			p.fail(key, "is required in "+c.Env)
+		}
+	}
+
+	if err := p.err(); err != nil {
+		return Config{}, err
+	}
+	return c, nil
+}
+
+// parser converts the values, collecting the problems so they are reported together
+type parser struct {
+	values   map[string]string
+	problems map[string]string
+}
+
+func (p *parser) fail(key, problem string) {
+	if p.problems == nil {
+		p.problems = map[string]string{}
+	}
+	if _, ok := p.problems[key]; !ok {
+		p.problems[key] = problem
+	}
+}
+
+func (p *parser) int(key string) int {
+	if p.values[key] == "" {
+		return 0
+	}
+	n, err := strconv.Atoi(p.values[key])
+	if err != nil {
+		p.fail(key, "must be a number")
+	}
+	return n
+}
+
+func (p *parser) duration(key string) time.Duration {
+	if p.values[key] == "" {
+		return 0
+	}
+	d, err := time.ParseDuration(p.values[key])
+	if err != nil {
+		p.fail(key, "must be a duration, like 30s or 1h")
+	} else if d < 0 {
+		p.fail(key, "can't be negative")
+	}
+	return d
+}
+
+func (p *parser) logLevel(key string) logging.Level {
+	level, err := logging.ParseLevel(p.values[key])
+	if err != nil {
+		p.fail(key, "must be debug, info, warn or error")
+	}
+	return level
+}
+
+func (p *parser) logFormat(key string) logging.Format {
+	format, err := logging.ParseFormat(p.values[key])
+	if err != nil {
+		p.fail(key, "must be human or json")
+	}
+	return format
+}
+
+// err describes every problem, sorted by key
+func (p *parser) err() error {
+	if len(p.problems) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(p.problems))
+	for key := range p.problems {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	descriptions := make([]string, len(keys))
+	for i, key := range keys {
+		descriptions[i] = key + " " + p.problems[key]
+	}
+	return fmt.Errorf("invalid configuration: %s", strings.Join(descriptions, ", "))
+}
@@ -0,0 +1,104 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/renanrt/lab-go-api/logging"
+	"github.com/stretchr/testify/assert"
+)
+
+func writeEnvFile(t *testing.T, content string) string {
+	f, err := ioutil.TempFile("", "env")
+	assert.NoError(t, err)
+	defer f.Close()
+	_, err = f.WriteString(content)
+	assert.NoError(t, err)
+	return f.Name()
+}
+
+func TestLoadDefaults(t *testing.T) {
+	cfg, err := load(nil, []string{"ENV=test"})
+	assert.NoError(t, err)
+	assert.Equal(t, EnvTest, cfg.Env)
+	assert.Equal(t, ":8080", cfg.Addr())
+	assert.Equal(t, logging.LevelInfo, cfg.LogLevel)
+	assert.Equal(t, logging.FormatHuman, cfg.LogFormat)
+	assert.Equal(t, time.Hour, cfg.Service.CacheTTL)
+	assert.Equal(t, 10000, cfg.Service.CacheSize)
+
+	// the environment is production when it's not set
+	_, err = load(nil, nil)
+	assert.EqualError(t, err, "invalid configuration: AUTH_JWT_KEYS is required in production, STORE_FILE is required in production")
+}
+
+// tests that the env file overrides the defaults, the environment overrides the file, and the flags override everything
+func TestLoadPriority(t *testing.T) {
+	path := writeEnvFile(t, `
+# comments and blank lines are skipped
+PORT=9000
+LOG_LEVEL=debug
+export LOG_FORMAT="json"
+AUTH_API_KEYS=key-1:retailer-1
+`)
+	defer os.Remove(path)
+
+	cfg, err := load([]string{"-port", "9002"}, []string{"ENV=test", "ENV_FILE=" + path, "PORT=9001", "LOG_LEVEL=warn"})
+	assert.NoError(t, err)
+	assert.Equal(t, 9002, cfg.Port)
+	assert.Equal(t, logging.LevelWarn, cfg.LogLevel)
+	assert.Equal(t, logging.FormatJSON, cfg.LogFormat)
+	assert.Equal(t, "key-1:retailer-1", cfg.Auth.APIKeys)
+}
+
+func TestLoadMissingEnvFile(t *testing.T) {
+	_, err := load([]string{"-env-file", "/does/not/exist"}, nil)
+	assert.Error(t, err)
+}
+
+func TestReadEnvFileInvalidLine(t *testing.T) {
+	path := writeEnvFile(t, "PORT=8080\nPORT 8080\n")
+	defer os.Remove(path)
+
+	_, err := ReadEnvFile(path)
+	assert.EqualError(t, err, path+":2: expected KEY=value")
+}
+
+func TestLoadInvalid(t *testing.T) {
+	_, err := load(nil, []string{"ENV_FILE=/dev/null", "ENV=staging", "PORT=http", "CACHE_TTL=1", "BATCH_PARALLELISM=-1"})
+	assert.EqualError(t, err, "invalid configuration: BATCH_PARALLELISM can't be negative, CACHE_TTL must be a duration, like 30s or 1h, "+
+		"ENV must be development, test or production, PORT must be a number")
+}
+
+func TestLoadProduction(t *testing.T) {
+	_, err := load([]string{"-env", "production", "-store-file", "/var/lib/lab-go-api/store.json"}, nil)
+	assert.EqualError(t, err, "invalid configuration: AUTH_JWT_KEYS is required in production")
+
+	cfg, err := load(nil, []string{"ENV=production", "AUTH_JWT_KEYS=prod:secret", "STORE_FILE=/var/lib/lab-go-api/store.json"})
+	assert.NoError(t, err)
+	assert.Equal(t, EnvProduction, cfg.Env)
+	assert.Equal(t, "prod:secret", cfg.Auth.JWTKeys)
+	assert.Equal(t, "/var/lib/lab-go-api/store.json", cfg.Service.StoreFile)
+}
+
+// tests that the development values of the default env file are only used in development
+func TestLoadDefaultEnvFile(t *testing.T) {
+	wd, err := os.Getwd()
+	assert.NoError(t, err)
+	assert.NoError(t, os.Chdir(".."))
+	defer os.Chdir(wd)
+
+	cfg, err := load(nil, []string{"ENV=development"})
+	assert.NoError(t, err)
+	assert.Equal(t, logging.LevelDebug, cfg.LogLevel)
+
+	cfg, err = load(nil, []string{"ENV=test"})
+	assert.NoError(t, err)
+	assert.Equal(t, logging.LevelInfo, cfg.LogLevel)
+
+	cfg, err = load(nil, []string{"AUTH_JWT_KEYS=prod:secret", "STORE_FILE=/var/lib/lab-go-api/store.json"})
+	assert.NoError(t, err)
+	assert.Equal(t, logging.LevelInfo, cfg.LogLevel)
+}
@@ -0,0 +1,102 @@
+package geo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/renanrt/lab-go-api/address"
+)
+
+// BoundaryGeocoder is an offline Geocoder that finds the jurisdiction boundary containing a point.
+// Boundaries are loaded from a GeoJSON FeatureCollection of polygons, where the properties of
+// each feature hold the address fields of the jurisdiction.
+type BoundaryGeocoder struct {
+	boundaries []*Boundary
+}
+
+// Boundary is the area of a jurisdiction
+type Boundary struct {
+	Address address.Address
+	// Rings are the outer ring followed by the holes, as [lng, lat] positions like GeoJSON
+	Rings [][][2]float64
+}
+
+// NewBoundaryGeocoder creates a geocoder with the boundaries. When boundaries overlap,
+// the first one containing the point is used, so smaller jurisdictions should come first.
+func NewBoundaryGeocoder(boundaries ...*Boundary) *BoundaryGeocoder {
+	return &BoundaryGeocoder{boundaries: boundaries}
+}
+
+type featureCollection struct {
+	Features []struct {
+		Properties address.Address `json:"properties"`
+		Geometry   struct {
+			Type        string         `json:"type"`
+			Coordinates [][][2]float64 `json:"coordinates"`
+		} `json:"geometry"`
+	} `json:"features"`
+}
+
+// LoadBoundaryGeocoder creates a geocoder from a GeoJSON boundary file
+func LoadBoundaryGeocoder(path string) (*BoundaryGeocoder, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	collection := featureCollection{}
+	if err := json.NewDecoder(f).Decode(&collection); err != nil {
+		return nil, err
+	}
+
+	boundaries := []*Boundary{}
+	for i, feature := range collection.Features {
+		if feature.Geometry.Type != "Polygon" {
+			return nil, fmt.Errorf("feature %d has geometry %q, only Polygon is supported", i, feature.Geometry.Type)
+		}
+		boundaries = append(boundaries, &Boundary{Address: feature.Properties.Normalize(), Rings: feature.Geometry.Coordinates})
+	}
+	return NewBoundaryGeocoder(boundaries...), nil
+}
+
+// ReverseGeocode returns the address of the first boundary that contains the point
+func (g *BoundaryGeocoder) ReverseGeocode(ctx context.Context, p Point) (address.Address, error) {
+	if err := ctx.Err(); err != nil {
+		return address.Address{}, err
+	}
+	for _, b := range g.boundaries {
+		if b.Contains(p) {
+			return b.Address, nil
+		}
+	}
+	return address.Address{}, ErrNotFound
+}
+
+// Contains checks if the point is inside the outer ring and outside the holes
+func (b *Boundary) Contains(p Point) bool {
+	if len(b.Rings) == 0 || !ringContains(b.Rings[0], p) {
+		return false
+	}
+	for _, hole := range b.Rings[1:] {
+		if ringContains(hole, p) {
+			return false
+		}
+	}
+	return true
+}
+
+// ringContains uses the ray casting algorithm
+func ringContains(ring [][2]float64, p Point) bool {
+	inside := false
+	for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+		xi, yi := ring[i][0], ring[i][1]
+		xj, yj := ring[j][0], ring[j][1]
+		if (yi > p.Lat) != (yj > p.Lat) && p.Lng < (xj-xi)*(p.Lat-yi)/(yj-yi)+xi {
+			inside = !inside
+		}
+	}
+	return inside
+}
@@ -0,0 +1,48 @@
+package geo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/renanrt/lab-go-api/address"
+	"github.com/renanrt/lab-go-api/apperrors"
+)
+
+// Point is a GPS position
+type Point struct {
+	Lat float64 `json:"lat"`
+	Lng float64 `json:"lng"`
+}
+
+// Geocoder resolves the address of the jurisdictions that contain a point
+type Geocoder interface {
+	ReverseGeocode(ctx context.Context, p Point) (address.Address, error)
+}
+
+// ErrNotFound is returned when no jurisdiction contains the point
+var ErrNotFound = errors.New("no jurisdiction found for the coordinates")
+
+// ParsePoint parses the lat and lng query parameters
+func ParsePoint(lat, lng string) (Point, error) {
+	fields := map[string]string{}
+
+	latValue, err := strconv.ParseFloat(lat, 64)
+	if err != nil || latValue < -90 || latValue > 90 {
+		fields["lat"] = "must be a number between -90 and 90"
+	}
+	lngValue, err := strconv.ParseFloat(lng, 64)
+	if err != nil || lngValue < -180 || lngValue > 180 {
+		fields["lng"] = "must be a number between -180 and 180"
+	}
+
+	if len(fields) > 0 {
+		return Point{}, apperrors.Invalid(apperrors.CodeInvalidCoordinates, "coordinates", fields)
+	}
+	return Point{Lat: latValue, Lng: lngValue}, nil
+}
+
+func (p Point) String() string {
+	return fmt.Sprintf("%f,%f", p.Lat, p.Lng)
+}
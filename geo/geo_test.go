@@ -0,0 +1,45 @@
+package geo
+
+import (
+	"context"
+	"testing"
+
+	"github.com/renanrt/lab-go-api/apperrors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePoint(t *testing.T) {
+	p, err := ParsePoint("34.09", "-118.36")
+	assert.NoError(t, err)
+	assert.Equal(t, Point{Lat: 34.09, Lng: -118.36}, p)
+
+	_, err = ParsePoint("91", "abc")
+	assert.Equal(t, map[string]string{
+		"lat": "must be a number between -90 and 90",
+		"lng": "must be a number between -180 and 180",
+	}, err.(*apperrors.Error).Fields)
+}
+
+func TestBoundaryGeocoder(t *testing.T) {
+	g, err := LoadBoundaryGeocoder("testdata/boundaries.json")
+	assert.NoError(t, err)
+
+	a, err := g.ReverseGeocode(context.Background(), Point{Lat: 34.09, Lng: -118.36})
+	assert.NoError(t, err)
+	assert.Equal(t, "West Hollywood", a.City)
+	assert.Equal(t, "90046", a.Zipcode)
+
+	a, err = g.ReverseGeocode(context.Background(), Point{Lat: 34.02, Lng: -118.49})
+	assert.NoError(t, err)
+	assert.Equal(t, "Santa Monica", a.City)
+
+	// inside the hole of the Santa Monica boundary
+	_, err = g.ReverseGeocode(context.Background(), Point{Lat: 34.012, Lng: -118.497})
+	assert.Equal(t, ErrNotFound, err)
+
+	_, err = g.ReverseGeocode(context.Background(), Point{Lat: 40.71, Lng: -74.00})
+	assert.Equal(t, ErrNotFound, err)
+
+	_, err = LoadBoundaryGeocoder("testdata/missing.json")
+	assert.Error(t, err)
+}
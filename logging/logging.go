@@ -0,0 +1,250 @@
+// Package logging writes leveled and structured logs, as JSON for log collectors or in
+// a human readable format for development.
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Level is the severity of a log entry
+type Level int
+
+// The levels, from the most verbose
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+var levelNames = []string{"debug", "info", "warn", "error"}
+
+func (l Level) String() string {
+	if l < LevelDebug || l > LevelError {
+		return "level(" + strconv.Itoa(int(l)) + ")"
+	}
+	return levelNames[l]
+}
+
+// ParseLevel parses the name of a level, like LOG_LEVEL. Empty is info.
+func ParseLevel(name string) (Level, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return LevelInfo, nil
+	}
+	if name == "warning" {
+		return LevelWarn, nil
+	}
+	for i, levelName := range levelNames {
+		if name == levelName {
+			return Level(i), nil
+		}
+	}
+	return LevelInfo, fmt.Errorf("unknown log level %q", name)
+}
+
+// Format is how the entries are written
+type Format int
+
+const (
+	// FormatHuman writes a line with the time, level, message and key=value fields
+	FormatHuman Format = iota
+	// FormatJSON writes a JSON object per line
+	FormatJSON
+)
+
+// ParseFormat parses the name of a format, like LOG_FORMAT. Empty, "human", "text" and
+// "debug" are the human format.
+func ParseFormat(name string) (Format, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", "human", "text", "debug":
+		return FormatHuman, nil
+	case "json":
+		return FormatJSON, nil
+	}
+	return FormatHuman, fmt.Errorf("unknown log format %q", name)
+}
+
+// Fields are the structured data of an entry
+type Fields map[string]interface{}
+
+// output is shared by a logger and the loggers created with With, so their lines don't interleave
+type output struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// Logger writes the entries at or above its level. Loggers are safe for concurrent use.
+type Logger struct {
+	out    *output
+	level  Level
+	format Format
+	fields Fields
+
+	// now is replaced in tests
+	now func() time.Time
+}
+
+// New creates a logger that writes to w
+func New(w io.Writer, level Level, format Format) *Logger {
+	return &Logger{out: &output{w: w}, level: level, format: format, now: time.Now}
+}
+
+// Discard is a logger that writes nothing
+var Discard = New(ioutil.Discard, LevelError+1, FormatHuman)
+
+// With returns a logger that adds the fields to every entry
+func (l *Logger) With(fields Fields) *Logger {
+	c := *l
+	c.fields = make(Fields, len(l.fields)+len(fields))
+	for key, value := range l.fields {
+		c.fields[key] = value
+	}
+	for key, value := range fields {
+		c.fields[key] = value
+	}
+	return &c
+}
+
+// Enabled checks if entries of the level are written
+func (l *Logger) Enabled(level Level) bool {
+	return level >= l.level
+}
+
+// Debug writes an entry with details for development
+func (l *Logger) Debug(msg string, fields ...Fields) {
+	l.log(LevelDebug, msg, fields)
+}
+
+// Info writes an entry about the normal operation
+func (l *Logger) Info(msg string, fields ...Fields) {
+	l.log(LevelInfo, msg, fields)
+}
+
+// Warn writes an entry about a problem that was handled
+func (l *Logger) Warn(msg string, fields ...Fields) {
+	l.log(LevelWarn, msg, fields)
+}
+
+// Error writes an entry about a failure
+func (l *Logger) Error(msg string, fields ...Fields) {
+	l.log(LevelError, msg, fields)
+}
+
+func (l *Logger) log(level Level, msg string, fields []Fields) {
+	if !l.Enabled(level) {
+		return
+	}
+
+	entry := make(Fields, len(l.fields))
+	for key, value := range l.fields {
+		entry[key] = value
+	}
+	for _, f := range fields {
+		for key, value := range f {
+			entry[key] = value
+		}
+	}
+
+	var line []byte
+	if l.format == FormatJSON {
+		line = formatJSON(l.now(), level, msg, entry)
+	} else {
+		line = formatHuman(l.now(), level, msg, entry)
+	}
+
+	l.out.mu.Lock()
+	defer l.out.mu.Unlock()
+	l.out.w.Write(line)
+}
+
+// formatJSON writes the entry as a JSON object, with time, level and msg before the fields
+func formatJSON(t time.Time, level Level, msg string, fields Fields) []byte {
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, `{"time":%q,"level":%q,"msg":%s`, t.UTC().Format(time.RFC3339Nano), level, jsonValue(msg))
+	for _, key := range sortedKeys(fields) {
+		fmt.Fprintf(buf, ",%s:%s", jsonValue(key), jsonValue(fields[key]))
+	}
+	buf.WriteString("}\n")
+	return buf.Bytes()
+}
+
+func jsonValue(value interface{}) []byte {
+	if err, ok := value.(error); ok {
+		value = err.Error()
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		data, _ = json.Marshal(fmt.Sprint(value))
+	}
+	return data
+}
+
+// formatHuman writes the entry like "2018-07-01T10:00:00Z ERROR request failed status=500"
+func formatHuman(t time.Time, level Level, msg string, fields Fields) []byte {
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "%s %-5s %s", t.Format(time.RFC3339), strings.ToUpper(level.String()), msg)
+	for _, key := range sortedKeys(fields) {
+		value := fmt.Sprint(fields[key])
+		if value == "" || strings.ContainsAny(value, " \t\n\"=") {
+			value = strconv.Quote(value)
+		}
+		buf.WriteString(" " + key + "=" + value)
+	}
+	buf.WriteString("\n")
+	return buf.Bytes()
+}
+
+func sortedKeys(fields Fields) []string {
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+var (
+	defaultMu     sync.RWMutex
+	defaultLogger = New(os.Stderr, LevelInfo, FormatHuman)
+)
+
+// Default returns the logger used when there is none in the context
+func Default() *Logger {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	return defaultLogger
+}
+
+// SetDefault replaces the default logger, usually at startup with the configured one
+func SetDefault(l *Logger) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultLogger = l
+}
+
+type contextKey struct{}
+
+// NewContext returns a context with the logger, usually one With the fields of the request
+func NewContext(ctx context.Context, l *Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, l)
+}
+
+// FromContext returns the logger of the context, or the default logger
+func FromContext(ctx context.Context) *Logger {
+	if l, ok := ctx.Value(contextKey{}).(*Logger); ok {
+		return l
+	}
+	return Default()
+}
@@ -0,0 +1,88 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestLogger(level Level, format Format) (*Logger, *bytes.Buffer) {
+	buf := &bytes.Buffer{}
+	l := New(buf, level, format)
+	l.now = func() time.Time { return time.Date(2018, time.July, 1, 10, 0, 0, 0, time.UTC) }
+	return l, buf
+}
+
+func TestParseLevel(t *testing.T) {
+	for name, level := range map[string]Level{"": LevelInfo, "debug": LevelDebug, " INFO ": LevelInfo, "warning": LevelWarn, "error": LevelError} {
+		parsed, err := ParseLevel(name)
+		assert.NoError(t, err, name)
+		assert.Equal(t, level, parsed, name)
+	}
+	_, err := ParseLevel("verbose")
+	assert.Error(t, err)
+}
+
+func TestParseFormat(t *testing.T) {
+	format, err := ParseFormat("json")
+	assert.NoError(t, err)
+	assert.Equal(t, FormatJSON, format)
+	format, err = ParseFormat("debug")
+	assert.NoError(t, err)
+	assert.Equal(t, FormatHuman, format)
+	_, err = ParseFormat("xml")
+	assert.Error(t, err)
+}
+
+func TestHumanFormat(t *testing.T) {
+	l, buf := newTestLogger(LevelInfo, FormatHuman)
+	l.With(Fields{"request_id": "abc"}).Error("request failed", Fields{"status": 500, "error": "dial tcp: timeout"})
+
+	assert.Equal(t, "2018-07-01T10:00:00Z ERROR request failed error=\"dial tcp: timeout\" request_id=abc status=500\n", buf.String())
+}
+
+func TestJSONFormat(t *testing.T) {
+	l, buf := newTestLogger(LevelInfo, FormatJSON)
+	l.With(Fields{"retailer": "retailer-1"}).Warn("lookup failed", Fields{"error": errors.New("timeout")})
+
+	entry := map[string]interface{}{}
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	assert.Equal(t, map[string]interface{}{
+		"time":     "2018-07-01T10:00:00Z",
+		"level":    "warn",
+		"msg":      "lookup failed",
+		"retailer": "retailer-1",
+		"error":    "timeout",
+	}, entry)
+}
+
+func TestLevels(t *testing.T) {
+	l, buf := newTestLogger(LevelWarn, FormatHuman)
+	l.Debug("debug")
+	l.Info("info")
+	assert.Empty(t, buf.String())
+	assert.False(t, l.Enabled(LevelInfo))
+
+	l.Warn("warn")
+	assert.Contains(t, buf.String(), "WARN  warn")
+}
+
+// tests that With doesn't change the fields of the parent logger
+func TestWith(t *testing.T) {
+	l, buf := newTestLogger(LevelInfo, FormatHuman)
+	l.With(Fields{"job_id": "job-1"})
+	l.Info("started")
+	assert.NotContains(t, buf.String(), "job_id")
+}
+
+func TestContext(t *testing.T) {
+	assert.Equal(t, Default(), FromContext(context.Background()))
+
+	l, _ := newTestLogger(LevelInfo, FormatHuman)
+	assert.Equal(t, l, FromContext(NewContext(context.Background(), l)))
+}
@@ -1,32 +1,51 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/renanrt/lab-go-api/api"
+	"github.com/renanrt/lab-go-api/config"
+	"github.com/renanrt/lab-go-api/logging"
 )
 
 func main() {
-
+	// The mode is the first argument, the flags of the configuration follow it
+	args := os.Args[1:]
 	mode := "api"
-	if len(os.Args) > 1 {
-		mode = os.Args[1]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		mode, args = args[0], args[1:]
+	}
+
+	cfg, err := config.Load(args)
+	if err == flag.ErrHelp {
+		os.Exit(0)
 	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	logger := logging.New(os.Stderr, cfg.LogLevel, cfg.LogFormat)
+	logging.SetDefault(logger)
+
 	switch mode {
 	case "api":
-		RunAPI()
+		RunAPI(cfg, logger)
 	case "migrate":
-		fmt.Printf("migrate mode")
+		logger.Info("migrate mode")
 
 	default:
-		fmt.Printf("Unknown mode")
+		logger.Error("unknown mode", logging.Fields{"mode": mode})
+		os.Exit(1)
 	}
 }
 
-func RunAPI() {
-	err := api.Serve()
+func RunAPI(cfg config.Config, logger *logging.Logger) {
+	err := api.Serve(cfg, logger)
 	if err != nil {
-		fmt.Printf("Error serving API")
+		logger.Error("error serving the API", logging.Fields{"error": err})
+		os.Exit(1)
 	}
 }
@@ -0,0 +1,64 @@
+package model
+
+import "time"
+
+// Scope limits what an API key can do
+type Scope string
+
+const (
+	// ScopeSearch read only access: tax lookups, jobs, saved taxes and rate changes
+	ScopeSearch Scope = "search"
+	// ScopeTaxesWrite allows creating and updating the retailer taxes
+	ScopeTaxesWrite Scope = "taxes:write"
+	// ScopeKeysManage allows managing the API keys of the retailer
+	ScopeKeysManage Scope = "keys:manage"
+)
+
+// IsValidScope checks if the scope is known
+func IsValidScope(s Scope) bool {
+	return s == ScopeSearch || s == ScopeTaxesWrite || s == ScopeKeysManage
+}
+
+// Represents an API key used by a partner integration of a retailer.
+//
+// Saved in DB. Only the hash of the key is kept, the key itself is shown once when it's created or rotated.
+//
+// swagger:model apiKey
+type APIKey struct {
+	// the id for this key
+	//
+	// read only: true
+	ID string `json:"id"`
+
+	//retailer ID
+	RetailerID string `json:"retailer_id"`
+
+	//a name to recognize the integration that uses the key
+	Name string `json:"name"`
+
+	//the first characters of the key, to recognize it without showing it
+	Prefix string `json:"prefix"`
+
+	//SHA-256 hash of the key, never returned by the api
+	Hash string `json:"hash,omitempty"`
+
+	//what the key is allowed to do
+	Scopes []Scope `json:"scopes"`
+
+	CreatedAt  time.Time  `json:"created_at"`
+	RotatedAt  *time.Time `json:"rotated_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+// IsRevoked checks if the key was revoked
+func (k *APIKey) IsRevoked() bool {
+	return k.RevokedAt != nil
+}
+
+// Copy returns a copy of the key and its scopes
+func (k *APIKey) Copy() *APIKey {
+	c := *k
+	c.Scopes = append([]Scope(nil), k.Scopes...)
+	return &c
+}
@@ -0,0 +1,74 @@
+package model
+
+import "sort"
+
+// Calculate returns the tax charged for a quantity of items with the given unit price.
+// The tax per item is the percentage rate (or the brackets, for tiered taxes) plus the
+// fixed amount, limited by the cap when there is one.
+func (tr *TaxRate) Calculate(price float64, quantity int) float64 {
+	return tr.calculateItem(price) * float64(quantity)
+}
+
+func (tr *TaxRate) calculateItem(price float64) float64 {
+	tax := tr.Amount
+	if len(tr.Brackets) > 0 {
+		for _, b := range tr.Brackets {
+			tax += b.Calculate(price)
+		}
+	} else {
+		tax += price * tr.Rate
+	}
+
+	if tr.Cap > 0 && tax > tr.Cap {
+		return tr.Cap
+	}
+	return tax
+}
+
+// Calculate returns the tax for the portion of the price inside the bracket
+func (b *TaxBracket) Calculate(price float64) float64 {
+	if price <= b.From {
+		return 0
+	}
+	upper := price
+	if b.To > 0 && b.To < price {
+		upper = b.To
+	}
+	return (upper - b.From) * b.Rate
+}
+
+// CalculateTax returns the total tax charged by the group for a quantity of items with
+// the given unit price. Taxes are applied by order, and compound taxes are calculated
+// over the price plus the taxes applied before them.
+func (tg *TaxGroup) CalculateTax(price float64, quantity int) float64 {
+	total := 0.0
+	for _, tr := range tg.sortedRates() {
+		base := price
+		if tr.Compound {
+			base += total
+		}
+		total += tr.calculateItem(base)
+	}
+	return total * float64(quantity)
+}
+
+func (tg *TaxGroup) sortedRates() []*TaxRate {
+	rates := make([]*TaxRate, len(tg.Rates))
+	copy(rates, tg.Rates)
+	sort.SliceStable(rates, func(i, j int) bool {
+		return rates[i].Order < rates[j].Order
+	})
+	return rates
+}
+
+func sameBrackets(b1, b2 []*TaxBracket) bool {
+	if len(b1) != len(b2) {
+		return false
+	}
+	for i := range b1 {
+		if *b1[i] != *b2[i] {
+			return false
+		}
+	}
+	return true
+}
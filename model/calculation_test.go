@@ -0,0 +1,63 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// tests a fixed amount charged per unit, like a bag fee
+func TestCalculateExcise(t *testing.T) {
+	bagFee := NewExciseTaxRate("Bag fee", 0.10)
+	assert.Equal(t, TaxTypeExcise, bagFee.Type)
+	assert.InDelta(t, 0.30, bagFee.Calculate(2.5, 3), 0.0000001)
+}
+
+// tests that the tax per item is limited by the cap
+func TestCalculateCap(t *testing.T) {
+	tr := NewTaxRate(TaxTypeSpecial, "Capped tax", 0.10)
+	tr.Cap = 5
+
+	assert.InDelta(t, 2, tr.Calculate(20, 1), 0.0000001)
+	assert.InDelta(t, 10, tr.Calculate(100, 2), 0.0000001)
+}
+
+// tests Tennessee single article tax: 2.75% over the portion between $1600 and $3200
+func TestCalculateTiered(t *testing.T) {
+	local := NewTieredTaxRate("Davidson County", &TaxBracket{From: 0, To: 1600, Rate: 0.0225})
+	singleArticle := NewTieredTaxRate("Single article", &TaxBracket{From: 1600, To: 3200, Rate: 0.0275})
+
+	assert.InDelta(t, 22.5, local.Calculate(1000, 1), 0.0000001)
+	assert.InDelta(t, 36, local.Calculate(5000, 1), 0.0000001)
+	assert.InDelta(t, 0, singleArticle.Calculate(1000, 1), 0.0000001)
+	assert.InDelta(t, 11, singleArticle.Calculate(2000, 1), 0.0000001)
+	assert.InDelta(t, 44, singleArticle.Calculate(5000, 1), 0.0000001)
+}
+
+// tests the group calculation mixing percentage, compound and fixed taxes
+func TestGroupCalculateTax(t *testing.T) {
+	tg := TaxGroup{}
+	tg.AddTaxRate(NewTaxRate(TaxTypeState, "GST", 0.05))
+	tg.AddTaxRate(NewCompoundTaxRate(TaxTypeState, "QST", 0.095, 1))
+	deposit := NewExciseTaxRate("Bottle deposit", 0.10)
+	deposit.Order = 2
+	tg.AddTaxRate(deposit)
+
+	// 100 * 0.05 + 105 * 0.095 + 0.10
+	assert.InDelta(t, 15.075, tg.CalculateTax(100, 1), 0.0000001)
+	assert.InDelta(t, 30.15, tg.CalculateTax(100, 2), 0.0000001)
+}
+
+// tests that fixed amounts and brackets are considered when matching taxes
+func TestIsSameTaxAmounts(t *testing.T) {
+	bagFee := NewExciseTaxRate("Bag fee", 0.10)
+	dbTax := bagFee.ToTax()
+	assert.True(t, IsSameTax(dbTax, bagFee))
+
+	dbTax.Amount = 0.05
+	assert.False(t, IsSameTax(dbTax, bagFee))
+
+	tiered := NewTieredTaxRate("Single article", &TaxBracket{From: 1600, To: 3200, Rate: 0.0275})
+	assert.True(t, IsSameTaxRate(tiered, NewTieredTaxRate("Single article", &TaxBracket{From: 1600, To: 3200, Rate: 0.0275})))
+	assert.False(t, IsSameTaxRate(tiered, NewTieredTaxRate("Single article", &TaxBracket{From: 1600, Rate: 0.0275})))
+}
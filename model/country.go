@@ -0,0 +1,99 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// CountryUS United States
+	CountryUS = "US"
+	// CountryCA Canada
+	CountryCA = "CA"
+	// CountryAU Australia
+	CountryAU = "AU"
+	// CountryNZ New Zealand
+	CountryNZ = "NZ"
+	// CountryGB United Kingdom
+	CountryGB = "GB"
+)
+
+// euCountries are the ISO codes of the EU member states, all of them use VAT
+var euCountries = []string{
+	"AT", "BE", "BG", "CY", "CZ", "DE", "DK", "EE", "ES", "FI", "FR", "GR", "HR", "HU",
+	"IE", "IT", "LT", "LU", "LV", "MT", "NL", "PL", "PT", "RO", "SE", "SI", "SK",
+}
+
+var vatTaxTypes = []TaxType{TaxTypeVAT}
+
+var countryTaxTypes = map[string][]TaxType{
+	CountryUS: {TaxTypeState, TaxTypeCounty, TaxTypeCity, TaxTypeSpecial, TaxTypeExcise, TaxTypeTiered},
+	CountryCA: {TaxTypeGST, TaxTypeHST, TaxTypePST, TaxTypeQST, TaxTypeExcise},
+	CountryAU: {TaxTypeGST},
+	CountryNZ: {TaxTypeGST},
+	CountryGB: vatTaxTypes,
+}
+
+func init() {
+	for _, c := range euCountries {
+		countryTaxTypes[c] = vatTaxTypes
+	}
+}
+
+// NormalizeCountry returns the upper case ISO code for a country.
+// An empty country is considered to be the US, which was the only supported country.
+func NormalizeCountry(country string) string {
+	c := strings.ToUpper(strings.TrimSpace(country))
+	switch c {
+	case "", "USA":
+		return CountryUS
+	case "UK":
+		return CountryGB
+	}
+	return c
+}
+
+// IsSupportedCountry checks if there are tax types defined for a country
+func IsSupportedCountry(country string) bool {
+	_, ok := countryTaxTypes[NormalizeCountry(country)]
+	return ok
+}
+
+// IsEUCountry checks if the country is an EU member state
+func IsEUCountry(country string) bool {
+	c := NormalizeCountry(country)
+	for _, eu := range euCountries {
+		if eu == c {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidTaxTypes returns the tax types that can be used in a country
+func ValidTaxTypes(country string) []TaxType {
+	return countryTaxTypes[NormalizeCountry(country)]
+}
+
+// IsValidTaxType checks if a tax type can be used in a country
+func IsValidTaxType(country string, t TaxType) bool {
+	for _, valid := range ValidTaxTypes(country) {
+		if IsSameType(valid, t) {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks that all tax rates of the group are valid for the country
+func (tg *TaxGroup) Validate(country string) error {
+	if !IsSupportedCountry(country) {
+		return fmt.Errorf("country %q is not supported", country)
+	}
+	for _, tr := range tg.Rates {
+		if !IsValidTaxType(country, tr.Type) {
+			return fmt.Errorf("tax %q has type %q which is not valid for country %s", tr.Name, tr.Type, NormalizeCountry(country))
+		}
+	}
+	return nil
+}
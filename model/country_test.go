@@ -0,0 +1,40 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeCountry(t *testing.T) {
+	assert.Equal(t, CountryUS, NormalizeCountry(""))
+	assert.Equal(t, CountryUS, NormalizeCountry(" usa "))
+	assert.Equal(t, CountryCA, NormalizeCountry("ca"))
+	assert.Equal(t, CountryGB, NormalizeCountry("UK"))
+}
+
+func TestIsValidTaxType(t *testing.T) {
+	assert.True(t, IsValidTaxType("US", TaxTypeState))
+	assert.False(t, IsValidTaxType("US", TaxTypeVAT))
+	assert.True(t, IsValidTaxType("CA", TaxTypeHST))
+	assert.True(t, IsValidTaxType("ca", " QST "))
+	assert.False(t, IsValidTaxType("CA", TaxTypeCounty))
+	assert.True(t, IsValidTaxType("NZ", TaxTypeGST))
+	assert.True(t, IsValidTaxType("DE", TaxTypeVAT))
+	assert.False(t, IsValidTaxType("DE", TaxTypeGST))
+	assert.False(t, IsValidTaxType("ZZ", TaxTypeVAT))
+	assert.True(t, IsEUCountry("fr"))
+	assert.False(t, IsEUCountry("GB"))
+}
+
+// tests that the country drives which tax types are valid in a group
+func TestTaxGroupValidate(t *testing.T) {
+	tg := TaxGroup{}
+	tg.AddTaxRate(NewTaxRate(TaxTypeGST, "GST", 0.05))
+	tg.AddTaxRate(NewTaxRate(TaxTypePST, "British Columbia PST", 0.07))
+
+	assert.NoError(t, tg.Validate("CA"))
+	assert.Error(t, tg.Validate("US"))
+	assert.Error(t, tg.Validate("AU"))
+	assert.Error(t, tg.Validate("ZZ"))
+}
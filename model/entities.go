@@ -1,10 +1,14 @@
 package model
 
-import "strings"
+import (
+	"strings"
+	"time"
+)
 
 const (
 	AVALARA = "avalara"
 	TAXJAR  = "taxjar"
+	OFFLINE = "offline"
 )
 
 // Represents a tax associated to a retailer.
@@ -41,6 +45,27 @@ type Tax struct {
 
 	// tax type
 	Type TaxType `json:"type"`
+
+	//whether this tax is levied on a base that includes the lower order taxes
+	Compound bool `json:"compound"`
+
+	//the position of this tax when applying compound taxes
+	Order int `json:"order"`
+
+	//fixed amount charged per unit, for excise taxes like bag fees or bottle deposits
+	Amount float64 `json:"amount,omitempty"`
+
+	//maximum tax charged per item. Zero means there is no cap
+	Cap float64 `json:"cap,omitempty"`
+
+	//the brackets used by tiered taxes
+	Brackets []*TaxBracket `json:"brackets,omitempty"`
+
+	//the period where this tax is effective. Used to schedule future rates
+	Validity
+
+	//codes that identify the jurisdiction of this tax
+	Jurisdiction
 }
 
 // SourceType Tax api providers
@@ -54,6 +79,8 @@ const (
 	SourceTypeAvalara SourceType = "avalara"
 	// SourceTypeTaxjar taxjar
 	SourceTypeTaxjar SourceType = "taxjar"
+	// SourceTypeOffline taxes loaded from the offline provider data
+	SourceTypeOffline SourceType = "offline"
 )
 
 const (
@@ -65,6 +92,20 @@ const (
 	TaxTypeCounty TaxType = "county"
 	// TaxTypeSpecial Special type - usually it's a district type
 	TaxTypeSpecial TaxType = "special"
+	// TaxTypeExcise Fixed amount charged per unit, like bag fees or bottle deposits
+	TaxTypeExcise TaxType = "excise"
+	// TaxTypeTiered Tax calculated over brackets of the item price, like Tennessee single article tax
+	TaxTypeTiered TaxType = "tiered"
+	// TaxTypeVAT Value added tax, used by EU countries and the UK
+	TaxTypeVAT TaxType = "vat"
+	// TaxTypeGST Goods and services tax, used by Canada (federal), Australia and New Zealand
+	TaxTypeGST TaxType = "gst"
+	// TaxTypeHST Harmonized sales tax, Canadian provinces that merged GST and PST
+	TaxTypeHST TaxType = "hst"
+	// TaxTypePST Provincial sales tax, Canada
+	TaxTypePST TaxType = "pst"
+	// TaxTypeQST Quebec sales tax
+	TaxTypeQST TaxType = "qst"
 )
 
 //TaxGroup represents a group of taxes
@@ -73,6 +114,10 @@ type TaxGroup struct {
 	//this field is used for confirming that the taxes were accepted
 	RequestID string     `json:"request_id"`
 	Rates     []*TaxRate `json:"rates"`
+	//names that distinguish this group from the other candidates of an ambiguous address
+	Jurisdictions []string `json:"jurisdictions,omitempty"`
+	//how likely this group is the right one for the address, from 0 to 1
+	Confidence float64 `json:"confidence,omitempty"`
 }
 
 //TaxRate represents a single tax
@@ -81,6 +126,42 @@ type TaxRate struct {
 	Rate      float64 `json:"rate"`
 	Name      string  `json:"name"`
 	Type      TaxType `json:"type"`
+	//compound taxes are calculated over the price plus all taxes with a lower order
+	Compound bool `json:"compound"`
+	Order    int  `json:"order"`
+	//fixed amount per unit
+	Amount float64 `json:"amount,omitempty"`
+	//max tax per item
+	Cap      float64       `json:"cap,omitempty"`
+	Brackets []*TaxBracket `json:"brackets,omitempty"`
+	Validity
+	Jurisdiction
+}
+
+//Validity represents the period where a tax rate is effective.
+//ValidFrom is inclusive and ValidTo is exclusive, a nil value means the period is open
+type Validity struct {
+	ValidFrom *time.Time `json:"valid_from,omitempty"`
+	ValidTo   *time.Time `json:"valid_to,omitempty"`
+}
+
+//IsActiveAt checks if the period contains the time
+func (v Validity) IsActiveAt(t time.Time) bool {
+	if v.ValidFrom != nil && t.Before(*v.ValidFrom) {
+		return false
+	}
+	if v.ValidTo != nil && !t.Before(*v.ValidTo) {
+		return false
+	}
+	return true
+}
+
+//TaxBracket represents the rate applied over a portion of the item price.
+//A zero To means the bracket has no upper limit
+type TaxBracket struct {
+	From float64 `json:"from"`
+	To   float64 `json:"to,omitempty"`
+	Rate float64 `json:"rate"`
 }
 
 //NewTaxRate Creates a new taxRate object
@@ -88,8 +169,103 @@ func NewTaxRate(t TaxType, name string, rate float64) *TaxRate {
 	return &TaxRate{Name: name, Rate: rate, Type: t}
 }
 
+//NewCompoundTaxRate Creates a new taxRate object that is levied on top of the taxes with a lower order
+func NewCompoundTaxRate(t TaxType, name string, rate float64, order int) *TaxRate {
+	return &TaxRate{Name: name, Rate: rate, Type: t, Compound: true, Order: order}
+}
+
+//NewExciseTaxRate Creates a new taxRate object that charges a fixed amount per unit
+func NewExciseTaxRate(name string, amount float64) *TaxRate {
+	return &TaxRate{Name: name, Amount: amount, Type: TaxTypeExcise}
+}
+
+//NewTieredTaxRate Creates a new taxRate object calculated over price brackets
+func NewTieredTaxRate(name string, brackets ...*TaxBracket) *TaxRate {
+	return &TaxRate{Name: name, Brackets: brackets, Type: TaxTypeTiered}
+}
+
 func (tr *TaxRate) ToTax() *Tax {
-	return &Tax{Name: tr.Name, Rate: tr.Rate, Type: tr.Type, VendTaxID: tr.VendTaxID}
+	return &Tax{
+		Name:         tr.Name,
+		Rate:         tr.Rate,
+		Type:         tr.Type,
+		VendTaxID:    tr.VendTaxID,
+		Compound:     tr.Compound,
+		Order:        tr.Order,
+		Amount:       tr.Amount,
+		Cap:          tr.Cap,
+		Brackets:     tr.Brackets,
+		Validity:     tr.Validity,
+		Jurisdiction: tr.Jurisdiction,
+	}
+}
+
+// Copy returns a copy of the group and its tax rates
+func (tg *TaxGroup) Copy() *TaxGroup {
+	c := *tg
+	c.Rates = make([]*TaxRate, 0, len(tg.Rates))
+	for _, tr := range tg.Rates {
+		rate := *tr
+		c.Rates = append(c.Rates, &rate)
+	}
+	c.Jurisdictions = append([]string(nil), tg.Jurisdictions...)
+	return &c
+}
+
+// CopyTaxGroups returns copies of the groups
+func CopyTaxGroups(taxGroups []*TaxGroup) []*TaxGroup {
+	copies := make([]*TaxGroup, 0, len(taxGroups))
+	for _, tg := range taxGroups {
+		copies = append(copies, tg.Copy())
+	}
+	return copies
+}
+
+// ActiveAt returns a copy of the group with the tax rates that are effective at the time
+func (tg *TaxGroup) ActiveAt(t time.Time) *TaxGroup {
+	active := &TaxGroup{RequestID: tg.RequestID, Jurisdictions: tg.Jurisdictions, Confidence: tg.Confidence}
+	for _, tr := range tg.Rates {
+		if tr.IsActiveAt(t) {
+			active.AddTaxRate(tr)
+		}
+	}
+	active.UpdateTotalRate()
+	return active
+}
+
+// ActiveTaxes returns the taxes that are effective at the time.
+// Stored taxes with a scheduled rate become active once their ValidFrom is reached.
+func ActiveTaxes(taxes []*Tax, t time.Time) []*Tax {
+	active := []*Tax{}
+	for _, tax := range taxes {
+		if tax.IsActiveAt(t) {
+			active = append(active, tax)
+		}
+	}
+	return active
+}
+
+// EffectiveRate returns the total rate of the group, respecting compound taxes.
+// Only percentage rates are considered: fixed amounts, caps and brackets depend on
+// the item price, see CalculateTax.
+// Rates are applied by order: a simple rate is added to the total, while a compound
+// rate is levied over the price plus the taxes applied before it.
+// Example: GST 5% (order 0) and QST 9.5% compound (order 1) = 0.05 + 0.095 * 1.05 = 0.14975
+func (tg *TaxGroup) EffectiveRate() float64 {
+	total := 0.0
+	for _, tr := range tg.sortedRates() {
+		if tr.Compound {
+			total += tr.Rate * (1 + total)
+		} else {
+			total += tr.Rate
+		}
+	}
+	return total
+}
+
+// UpdateTotalRate sets the TotalRate field with the effective rate of the group
+func (tg *TaxGroup) UpdateTotalRate() {
+	tg.TotalRate = tg.EffectiveRate()
 }
 
 // GetTaxByType returns all tax rates that matches a type
@@ -152,32 +328,84 @@ func (tg *TaxGroup) ContainsParentId(parentID string) bool {
 // Example:
 // The list 'taxesWithNoParent' contains all taxes from a retailer where the parentID is empty.
 // Ideally, just State taxes have empty parentID's. Alll othe ones should have a parentID set
+// The taxes are compared with the DefaultMatcher, see FillTaxParentIdsWithMatcher for fuzzy matching
 func (tg *TaxGroup) FillTaxParentIds(taxesWithNoParent []*Tax) {
+	tg.FillTaxParentIdsWithMatcher(taxesWithNoParent, DefaultMatcher)
+}
+
+// ParentMatch reports which saved tax was matched with a state tax rate, and why
+type ParentMatch struct {
+	TaxName   string `json:"tax_name"`
+	VendTaxID string `json:"vend_tax_id"`
+	MatchResult
+}
+
+// FillTaxParentIdsWithMatcher works like FillTaxParentIds, comparing the taxes with the matcher.
+// Each state tax rate gets the saved tax with the highest score, the first one in the list when
+// there is a tie. It returns the chosen matches, so the reason can be reported.
+func (tg *TaxGroup) FillTaxParentIdsWithMatcher(taxesWithNoParent []*Tax, matcher TaxMatcher) []*ParentMatch {
+	matches := []*ParentMatch{}
 	for _, apiTaxRate := range tg.GetTaxByType(TaxTypeState) {
-		for _, dbTax := range taxesWithNoParent {
-			if dbTax.ParentId != "" {
-				continue
-			}
-			if IsSameTax(dbTax, apiTaxRate) {
-				apiTaxRate.VendTaxID = dbTax.VendTaxID
+		if dbTax, result := bestMatch(apiTaxRate, taxesWithNoParent, matcher, true); dbTax != nil {
+			apiTaxRate.VendTaxID = dbTax.VendTaxID
+			matches = append(matches, &ParentMatch{TaxName: apiTaxRate.Name, VendTaxID: dbTax.VendTaxID, MatchResult: result})
+		}
+	}
+	return matches
+}
+
+// bestMatch returns the tax with the highest score, the first one in the list when there is a tie,
+// or nil. The search stops at the first exact or jurisdiction code match. When parentsOnly is set,
+// the taxes with a parent are skipped.
+func bestMatch(apiTaxRate *TaxRate, taxes []*Tax, matcher TaxMatcher, parentsOnly bool) (*Tax, MatchResult) {
+	var best *Tax
+	var bestResult MatchResult
+	for _, dbTax := range taxes {
+		if parentsOnly && dbTax.ParentId != "" {
+			continue
+		}
+		result := matcher.Match(dbTax, apiTaxRate)
+		if result.Matched && (best == nil || result.Score > bestResult.Score) {
+			best, bestResult = dbTax, result
+			if result.Score >= 1 {
+				break
 			}
 		}
 	}
+	return best, bestResult
 }
 
 // IsSameTax determines if an apiTaxRate is the same from the one saved in DB
 func IsSameTax(dbTax *Tax, apiTaxRate *TaxRate) bool {
-	if !IsSameType(dbTax.Type, apiTaxRate.Type) {
+	if !IsSameType(dbTax.Type, apiTaxRate.Type) || dbTax.Compound != apiTaxRate.Compound {
+		return false
+	}
+	if dbTax.Amount != apiTaxRate.Amount || dbTax.Cap != apiTaxRate.Cap || !sameBrackets(dbTax.Brackets, apiTaxRate.Brackets) {
 		return false
 	}
-	return dbTax.Rate == apiTaxRate.Rate && strings.EqualFold(strings.TrimSpace(dbTax.Name), strings.TrimSpace(apiTaxRate.Name))
+	if dbTax.Rate != apiTaxRate.Rate {
+		return false
+	}
+	if same, known := dbTax.Jurisdiction.IsSame(apiTaxRate.Jurisdiction); known {
+		return same
+	}
+	return strings.EqualFold(strings.TrimSpace(dbTax.Name), strings.TrimSpace(apiTaxRate.Name))
 }
 
 func IsSameTaxRate(taxRate1, taxRate2 *TaxRate) bool {
-	if !IsSameType(taxRate1.Type, taxRate2.Type) {
+	if !IsSameType(taxRate1.Type, taxRate2.Type) || taxRate1.Compound != taxRate2.Compound {
+		return false
+	}
+	if taxRate1.Amount != taxRate2.Amount || taxRate1.Cap != taxRate2.Cap || !sameBrackets(taxRate1.Brackets, taxRate2.Brackets) {
+		return false
+	}
+	if taxRate1.Rate != taxRate2.Rate {
 		return false
 	}
-	return taxRate1.Rate == taxRate2.Rate && strings.EqualFold(strings.TrimSpace(taxRate1.Name), strings.TrimSpace(taxRate2.Name))
+	if same, known := taxRate1.Jurisdiction.IsSame(taxRate2.Jurisdiction); known {
+		return same
+	}
+	return strings.EqualFold(strings.TrimSpace(taxRate1.Name), strings.TrimSpace(taxRate2.Name))
 }
 
 func IsAvalara(provider string) bool {
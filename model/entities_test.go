@@ -73,3 +73,36 @@ func TestIsSameTaxType(t *testing.T) {
 	t2 = " State "
 	assert.True(t, IsSameType(t1, t2))
 }
+
+// tests the total rate of a group with compound taxes
+func TestEffectiveRateCompound(t *testing.T) {
+	tg := TaxGroup{}
+	tg.AddTaxRate(NewCompoundTaxRate(TaxTypeState, "QST", 0.095, 1))
+	tg.AddTaxRate(NewTaxRate(TaxTypeState, "GST", 0.05))
+
+	assert.InDelta(t, 0.14975, tg.EffectiveRate(), 0.0000001)
+
+	tg.UpdateTotalRate()
+	assert.InDelta(t, 0.14975, tg.TotalRate, 0.0000001)
+}
+
+// tests that a group without compound taxes just sums the rates
+func TestEffectiveRateSimple(t *testing.T) {
+	tg := TaxGroup{}
+	tg.AddTaxRate(NewTaxRate(TaxTypeState, "California", 0.0625))
+	tg.AddTaxRate(NewTaxRate(TaxTypeCity, "Santa Monica", 0.01))
+
+	assert.InDelta(t, 0.0725, tg.EffectiveRate(), 0.0000001)
+}
+
+// tests that the compound flag is kept when converting and matching taxes
+func TestCompoundToTax(t *testing.T) {
+	qst := NewCompoundTaxRate(TaxTypeState, "QST", 0.095, 1)
+	dbTax := qst.ToTax()
+	assert.True(t, dbTax.Compound)
+	assert.Equal(t, 1, dbTax.Order)
+	assert.True(t, IsSameTax(dbTax, qst))
+
+	dbTax.Compound = false
+	assert.False(t, IsSameTax(dbTax, qst))
+}
@@ -0,0 +1,160 @@
+package model
+
+import (
+	"math"
+	"sort"
+	"strings"
+)
+
+// TaxIndex indexes taxes by normalized type, name and rate, so looking up a tax rate
+// doesn't need to scan and normalize the whole list. It can be reused across calls,
+// as long as the indexed taxes are not modified.
+//
+// Taxes are found when they have the same rate and either the same normalized name or
+// one of the same jurisdiction codes. Names that are just similar, or rates inside a
+// tolerance, are only compared with the taxes of the same type.
+type TaxIndex struct {
+	normalizer  *NameNormalizer
+	byKey       map[indexKey][]*Tax
+	byCode      map[indexKey][]*Tax
+	byType      map[TaxType][]*Tax
+	byVendTaxID map[string][]*Tax
+	// positions are the positions of the taxes in the indexed list, so ties are broken like a linear scan
+	positions map[*Tax]int
+}
+
+type indexKey struct {
+	taxType TaxType
+	name    string
+	rate    int64
+}
+
+// NewTaxIndex creates an index over the taxes, normalizing the names with the default rules
+func NewTaxIndex(taxes []*Tax) *TaxIndex {
+	return NewTaxIndexWithNormalizer(taxes, NewNameNormalizer())
+}
+
+// NewTaxIndexWithNormalizer creates an index over the taxes, normalizing the names with the normalizer
+func NewTaxIndexWithNormalizer(taxes []*Tax, normalizer *NameNormalizer) *TaxIndex {
+	idx := &TaxIndex{
+		normalizer:  normalizer,
+		byKey:       make(map[indexKey][]*Tax, len(taxes)),
+		byCode:      map[indexKey][]*Tax{},
+		byType:      map[TaxType][]*Tax{},
+		byVendTaxID: make(map[string][]*Tax, len(taxes)),
+		positions:   make(map[*Tax]int, len(taxes)),
+	}
+	for i, tax := range taxes {
+		if _, ok := idx.positions[tax]; !ok {
+			idx.positions[tax] = i
+		}
+		key := idx.key(tax.Type, tax.Name, tax.Rate)
+		idx.byKey[key] = append(idx.byKey[key], tax)
+		idx.byType[key.taxType] = append(idx.byType[key.taxType], tax)
+		for _, code := range tax.Jurisdiction.codes() {
+			key.name = code
+			idx.byCode[key] = append(idx.byCode[key], tax)
+		}
+		if tax.VendTaxID != "" {
+			idx.byVendTaxID[tax.VendTaxID] = append(idx.byVendTaxID[tax.VendTaxID], tax)
+		}
+	}
+	return idx
+}
+
+// Index creates an index over the tax rates of the group
+func (tg *TaxGroup) Index() *TaxIndex {
+	taxes := make([]*Tax, 0, len(tg.Rates))
+	for _, tr := range tg.Rates {
+		taxes = append(taxes, tr.ToTax())
+	}
+	return NewTaxIndex(taxes)
+}
+
+// Candidates returns the taxes with the same type and rate of the tax rate, and the same
+// normalized name or one of the same jurisdiction codes, in the order of the indexed list
+func (idx *TaxIndex) Candidates(tr *TaxRate) []*Tax {
+	key := idx.key(tr.Type, tr.Name, tr.Rate)
+	candidates := idx.byKey[key]
+
+	codes := tr.Jurisdiction.codes()
+	if len(codes) == 0 || len(idx.byCode) == 0 {
+		return candidates
+	}
+
+	candidates = append([]*Tax{}, candidates...)
+	found := make(map[*Tax]bool, len(candidates))
+	for _, tax := range candidates {
+		found[tax] = true
+	}
+	for _, code := range codes {
+		key.name = code
+		for _, tax := range idx.byCode[key] {
+			if !found[tax] {
+				found[tax] = true
+				candidates = append(candidates, tax)
+			}
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return idx.positions[candidates[i]] < idx.positions[candidates[j]]
+	})
+	return candidates
+}
+
+// candidatesFor returns the taxes that the matcher may match with the tax rate. The exact matcher
+// only matches the Candidates, other matchers are compared with every tax of the same type.
+func (idx *TaxIndex) candidatesFor(tr *TaxRate, matcher TaxMatcher) []*Tax {
+	switch matcher.(type) {
+	case ExactMatcher, *ExactMatcher:
+		return idx.Candidates(tr)
+	}
+	return idx.byType[idx.key(tr.Type, "", 0).taxType]
+}
+
+// Match returns the indexed tax that best matches the tax rate, chosen like
+// FillTaxParentIdsWithMatcher chooses the parents, or nil
+func (idx *TaxIndex) Match(tr *TaxRate, matcher TaxMatcher) (*Tax, MatchResult) {
+	return bestMatch(tr, idx.candidatesFor(tr, matcher), matcher, false)
+}
+
+// Contains checks if there is a tax that IsSameTax as the tax rate
+func (idx *TaxIndex) Contains(tr *TaxRate) bool {
+	for _, tax := range idx.Candidates(tr) {
+		if IsSameTax(tax, tr) {
+			return true
+		}
+	}
+	return false
+}
+
+// ContainsVendTaxID checks if there is a tax with the vend tax id
+func (idx *TaxIndex) ContainsVendTaxID(vendTaxID string) bool {
+	if vendTaxID == "" {
+		return false
+	}
+	return len(idx.byVendTaxID[vendTaxID]) > 0
+}
+
+// FillTaxParentIdsFromIndex works like FillTaxParentIdsWithMatcher over the indexed taxes, with
+// the same result, but only compares the state taxes with the candidates of the matcher.
+func (tg *TaxGroup) FillTaxParentIdsFromIndex(idx *TaxIndex, matcher TaxMatcher) []*ParentMatch {
+	matches := []*ParentMatch{}
+	for _, apiTaxRate := range tg.GetTaxByType(TaxTypeState) {
+		if dbTax, result := bestMatch(apiTaxRate, idx.candidatesFor(apiTaxRate, matcher), matcher, true); dbTax != nil {
+			apiTaxRate.VendTaxID = dbTax.VendTaxID
+			matches = append(matches, &ParentMatch{TaxName: apiTaxRate.Name, VendTaxID: dbTax.VendTaxID, MatchResult: result})
+		}
+	}
+	return matches
+}
+
+// key normalizes the type and name, and rounds the rate to avoid floating point noise
+func (idx *TaxIndex) key(t TaxType, name string, rate float64) indexKey {
+	normalizedType := TaxType(strings.TrimSpace(foldName(string(t))))
+	return indexKey{
+		taxType: normalizedType,
+		name:    idx.normalizer.Normalize(name, normalizedType),
+		rate:    int64(math.Floor(rate*1e6 + 0.5)),
+	}
+}
@@ -0,0 +1,166 @@
+package model
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTaxIndexContains(t *testing.T) {
+	tg := TaxGroup{}
+	stateTax := NewTaxRate(TaxTypeState, "California", 0.0065)
+	stateTax.VendTaxID = "vend-tax-id"
+	cityTax := NewTaxRate(TaxTypeCity, "Santa Monica", 0.001)
+	tg.AddTaxRate(stateTax)
+	tg.AddTaxRate(cityTax)
+
+	idx := tg.Index()
+	assert.True(t, idx.Contains(stateTax))
+	assert.True(t, idx.Contains(NewTaxRate(" State ", " caLiFornia ", 0.0065)))
+	assert.True(t, idx.Contains(cityTax))
+	assert.False(t, idx.Contains(NewTaxRate(TaxTypeCity, "AnotherCity", 0.001)))
+	assert.False(t, idx.Contains(NewTaxRate(TaxTypeCity, "Santa Monica", 0.002)))
+
+	assert.True(t, idx.ContainsVendTaxID("vend-tax-id"))
+	assert.False(t, idx.ContainsVendTaxID(""))
+	assert.False(t, idx.ContainsVendTaxID("another-id"))
+}
+
+func TestTaxIndexCandidates(t *testing.T) {
+	dbTax := &Tax{Name: "County of Los Angeles", Rate: 0.0025, Type: TaxTypeCounty}
+	idx := NewTaxIndex([]*Tax{dbTax, {Name: "Orange County", Rate: 0.0025, Type: TaxTypeCounty}})
+
+	candidates := idx.Candidates(NewTaxRate(TaxTypeCounty, "LOS ANGELES CO", 0.0025))
+	assert.Equal(t, []*Tax{dbTax}, candidates)
+	assert.Empty(t, idx.Candidates(NewTaxRate(TaxTypeCity, "LOS ANGELES CO", 0.0025)))
+}
+
+// tests that the index is reused across tax groups
+func TestFillTaxParentIdsFromIndex(t *testing.T) {
+	dbStateTax := &Tax{Name: "California", Rate: 0.0065, Type: TaxTypeState, VendTaxID: "myVendTaxId"}
+	dbCityTax := &Tax{Name: "NYC", Rate: 0.0065, Type: TaxTypeCity, VendTaxID: "myVendCityTaxId", ParentId: dbStateTax.VendTaxID}
+	idx := NewTaxIndex([]*Tax{dbStateTax, dbCityTax})
+
+	for i := 0; i < 2; i++ {
+		tg := TaxGroup{}
+		stateTax := NewTaxRate(TaxTypeState, "CALIFORNIA", 0.0065)
+		tg.AddTaxRate(stateTax)
+		tg.AddTaxRate(NewTaxRate(TaxTypeCity, "Santa Monica", 0.001))
+
+		matches := tg.FillTaxParentIdsFromIndex(idx, DefaultMatcher)
+		assert.Len(t, matches, 1)
+		assert.Equal(t, "myVendTaxId", stateTax.VendTaxID)
+	}
+}
+
+// tests that the index chooses the same parents as the linear scan, for any matcher
+func TestFillTaxParentIdsFromIndexSameAsLinear(t *testing.T) {
+	texas := &Tax{Name: "State of Texas", Rate: 0.0625, Type: TaxTypeState, VendTaxID: "tx-code"}
+	texas.FIPSCode = "48"
+	taxes := []*Tax{
+		{Name: "Kalifornia", Rate: 0.0725, Type: TaxTypeState, VendTaxID: "ca-similar"},
+		{Name: "California", Rate: 0.0725, Type: TaxTypeState, VendTaxID: "ca-child", ParentId: "ca"},
+		{Name: "Tx", Rate: 0.0625, Type: TaxTypeState, VendTaxID: "tx-name"},
+		{Name: "CALIFORNIA", Rate: 0.0725, Type: TaxTypeState, VendTaxID: "ca-exact"},
+		texas,
+		{Name: "California", Rate: 0.0725, Type: TaxTypeState, VendTaxID: "ca-exact-2"},
+		{Name: "New York", Rate: 0.0401, Type: TaxTypeState, VendTaxID: "ny-tolerance"},
+		{Name: "Nevada", Rate: 0.0685, Type: TaxTypeCounty, VendTaxID: "nv-county"},
+	}
+	tg := &TaxGroup{}
+	tg.AddTaxRate(NewTaxRate(TaxTypeState, "California", 0.0725))
+	tx := NewTaxRate(TaxTypeState, "Texas", 0.0625)
+	tx.FIPSCode = "48"
+	tg.AddTaxRate(tx)
+	tg.AddTaxRate(NewTaxRate(TaxTypeState, "New York State", 0.04))
+	tg.AddTaxRate(NewTaxRate(TaxTypeState, "Nevada", 0.0685))
+
+	idx := NewTaxIndex(taxes)
+	for _, matcher := range []TaxMatcher{ExactMatcher{}, NewFuzzyMatcher(), &FuzzyMatcher{RateTolerance: 0.001, MinScore: 0.5}} {
+		linear, indexed := tg.Copy(), tg.Copy()
+		assert.Equal(t, linear.FillTaxParentIdsWithMatcher(taxes, matcher), indexed.FillTaxParentIdsFromIndex(idx, matcher))
+		assert.Equal(t, linear, indexed)
+	}
+
+	matches := tg.FillTaxParentIdsFromIndex(idx, &FuzzyMatcher{RateTolerance: 0.001, MinScore: 0.5})
+	assert.Len(t, matches, 3)
+	assert.Equal(t, "ca-exact", matches[0].VendTaxID)
+	assert.Equal(t, "tx-code", matches[1].VendTaxID)
+	assert.Equal(t, "ny-tolerance", matches[2].VendTaxID)
+}
+
+// newBenchmarkTaxes creates n saved taxes and a group with n state tax rates,
+// which is the size of a retailer with thousands of taxes
+func newBenchmarkTaxes(n int) ([]*Tax, *TaxGroup) {
+	taxes := make([]*Tax, 0, n)
+	tg := &TaxGroup{}
+	for i := 0; i < n; i++ {
+		name := fmt.Sprintf("County of District %d", i)
+		taxes = append(taxes, &Tax{Name: name, Rate: 0.0025, Type: TaxTypeState, VendTaxID: fmt.Sprintf("vend-%d", i)})
+		tg.AddTaxRate(NewTaxRate(TaxTypeState, name, 0.0025))
+	}
+	return taxes, tg
+}
+
+// The linear and indexed benchmarks use the same matcher, so they find the same parents
+func BenchmarkFillTaxParentIdsLinear(b *testing.B) {
+	taxes, tg := newBenchmarkTaxes(500)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tg.FillTaxParentIdsWithMatcher(taxes, DefaultMatcher)
+	}
+}
+
+func BenchmarkFillTaxParentIdsIndexed(b *testing.B) {
+	taxes, tg := newBenchmarkTaxes(500)
+	idx := NewTaxIndex(taxes)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tg.FillTaxParentIdsFromIndex(idx, DefaultMatcher)
+	}
+}
+
+func BenchmarkContainsTaxRateLinear(b *testing.B) {
+	taxes, tg := newBenchmarkTaxes(500)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, tax := range taxes {
+			tg.ContainsTaxRate(NewTaxRate(tax.Type, tax.Name, tax.Rate))
+		}
+	}
+}
+
+func BenchmarkContainsTaxRateIndexed(b *testing.B) {
+	taxes, tg := newBenchmarkTaxes(500)
+	idx := tg.Index()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, tax := range taxes {
+			idx.Contains(NewTaxRate(tax.Type, tax.Name, tax.Rate))
+		}
+	}
+}
+
+func BenchmarkContainsParentIdLinear(b *testing.B) {
+	taxes, tg := newBenchmarkTaxes(500)
+	tg.FillTaxParentIdsFromIndex(NewTaxIndex(taxes), DefaultMatcher)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, tax := range taxes {
+			tg.ContainsParentId(tax.VendTaxID)
+		}
+	}
+}
+
+func BenchmarkContainsParentIdIndexed(b *testing.B) {
+	taxes, tg := newBenchmarkTaxes(500)
+	tg.FillTaxParentIdsFromIndex(NewTaxIndex(taxes), DefaultMatcher)
+	idx := tg.Index()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, tax := range taxes {
+			idx.ContainsVendTaxID(tax.VendTaxID)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package model
+
+import "time"
+
+// JobStatus is the state of a bulk lookup job
+type JobStatus string
+
+const (
+	// JobStatusPending job was submitted and didn't start yet
+	JobStatusPending JobStatus = "pending"
+	// JobStatusRunning job is processing the addresses
+	JobStatusRunning JobStatus = "running"
+	// JobStatusCompleted all addresses were processed
+	JobStatusCompleted JobStatus = "completed"
+	// JobStatusFailed job stopped because of an error
+	JobStatusFailed JobStatus = "failed"
+	// JobStatusCancelled job was cancelled by the client
+	JobStatusCancelled JobStatus = "cancelled"
+)
+
+// Job represents an asynchronous bulk lookup of addresses.
+//
+// Saved in DB, so jobs survive process restarts.
+//
+// swagger:model job
+type Job struct {
+	ID         string    `json:"id"`
+	RetailerID string    `json:"retailer_id"`
+	Status     JobStatus `json:"status"`
+	Provider   string    `json:"provider,omitempty"`
+	AsOf       time.Time `json:"as_of"`
+	// Total is the number of addresses
+	Total int `json:"total"`
+	// Processed is the number of addresses looked up so far, the job resumes from here
+	Processed int `json:"processed"`
+	// Failed is the number of addresses that returned an error
+	Failed    int       `json:"failed"`
+	Error     string    `json:"error,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	Items   []*JobItem   `json:"items,omitempty"`
+	Results []*JobResult `json:"results,omitempty"`
+}
+
+// JobItem is an address of a job
+type JobItem struct {
+	ID      string `json:"id,omitempty"`
+	Country string `json:"country"`
+	State   string `json:"state,omitempty"`
+	City    string `json:"city,omitempty"`
+	Zipcode string `json:"zipcode"`
+	Street  string `json:"street,omitempty"`
+}
+
+// JobResult is the result of the lookup of a JobItem
+type JobResult struct {
+	ID         string      `json:"id,omitempty"`
+	Status     int         `json:"status"`
+	Candidates []*TaxGroup `json:"data,omitempty"`
+	Error      string      `json:"error,omitempty"`
+}
+
+// IsFinished checks if the job won't be processed anymore
+func (j *Job) IsFinished() bool {
+	return j.Status == JobStatusCompleted || j.Status == JobStatusFailed || j.Status == JobStatusCancelled
+}
+
+// Summary returns a copy of the job without the items and results, used to report progress
+func (j *Job) Summary() *Job {
+	s := *j
+	s.Items = nil
+	s.Results = nil
+	return &s
+}
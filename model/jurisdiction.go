@@ -0,0 +1,50 @@
+package model
+
+import "strings"
+
+// Jurisdiction holds the codes that identify a jurisdiction. Names are a poor identity key,
+// so the codes are preferred when comparing taxes.
+type Jurisdiction struct {
+	// FIPS code of the state, county or place. Example: 06037 for Los Angeles County
+	FIPSCode string `json:"fips_code,omitempty"`
+	// AvalaraCode is the jurisdiction code used by Avalara
+	AvalaraCode string `json:"avalara_code,omitempty"`
+	// TaxJarCode is the region code used by TaxJar
+	TaxJarCode string `json:"taxjar_code,omitempty"`
+}
+
+// IsSame compares the first code that both jurisdictions have, in the order FIPS, Avalara and TaxJar.
+// known is false when there is no code on both sides, so the taxes need to be compared by name.
+func (j Jurisdiction) IsSame(other Jurisdiction) (same bool, known bool) {
+	pairs := [][2]string{
+		{j.FIPSCode, other.FIPSCode},
+		{j.AvalaraCode, other.AvalaraCode},
+		{j.TaxJarCode, other.TaxJarCode},
+	}
+	for _, p := range pairs {
+		c1, c2 := normalizeCode(p[0]), normalizeCode(p[1])
+		if c1 != "" && c2 != "" {
+			return c1 == c2, true
+		}
+	}
+	return false, false
+}
+
+// codes returns all codes of the jurisdiction, prefixed by their kind
+func (j Jurisdiction) codes() []string {
+	codes := []string{}
+	if c := normalizeCode(j.FIPSCode); c != "" {
+		codes = append(codes, "fips:"+c)
+	}
+	if c := normalizeCode(j.AvalaraCode); c != "" {
+		codes = append(codes, "avalara:"+c)
+	}
+	if c := normalizeCode(j.TaxJarCode); c != "" {
+		codes = append(codes, "taxjar:"+c)
+	}
+	return codes
+}
+
+func normalizeCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
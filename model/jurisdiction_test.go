@@ -0,0 +1,61 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJurisdictionIsSame(t *testing.T) {
+	la := Jurisdiction{FIPSCode: "06037", AvalaraCode: "037"}
+
+	same, known := la.IsSame(Jurisdiction{FIPSCode: " 06037 "})
+	assert.True(t, known)
+	assert.True(t, same)
+
+	same, known = la.IsSame(Jurisdiction{FIPSCode: "06059", AvalaraCode: "037"})
+	assert.True(t, known)
+	assert.False(t, same)
+
+	// FIPS is missing on one side, so the Avalara code is used
+	same, known = la.IsSame(Jurisdiction{AvalaraCode: "037"})
+	assert.True(t, known)
+	assert.True(t, same)
+
+	_, known = la.IsSame(Jurisdiction{TaxJarCode: "LA"})
+	assert.False(t, known)
+}
+
+// tests that jurisdiction codes are preferred over names when both sides have them
+func TestIsSameTaxJurisdiction(t *testing.T) {
+	apiTaxRate := NewTaxRate(TaxTypeCounty, "Los Angeles County", 0.0025)
+	apiTaxRate.FIPSCode = "06037"
+
+	dbTax := &Tax{Name: "LA", Rate: 0.0025, Type: TaxTypeCounty}
+	assert.False(t, IsSameTax(dbTax, apiTaxRate))
+
+	dbTax.FIPSCode = "06037"
+	assert.True(t, IsSameTax(dbTax, apiTaxRate))
+	assert.True(t, NewFuzzyMatcher().Match(dbTax, apiTaxRate).Matched)
+
+	dbTax.Name = "Los Angeles County"
+	dbTax.FIPSCode = "06059"
+	assert.False(t, IsSameTax(dbTax, apiTaxRate))
+	assert.False(t, NewFuzzyMatcher().Match(dbTax, apiTaxRate).Matched)
+
+	// falls back to the name when the saved tax has no codes
+	dbTax.FIPSCode = ""
+	assert.True(t, IsSameTax(dbTax, apiTaxRate))
+}
+
+func TestTaxIndexJurisdiction(t *testing.T) {
+	dbTax := &Tax{Name: "LA", Rate: 0.0025, Type: TaxTypeCounty, Jurisdiction: Jurisdiction{AvalaraCode: "037"}}
+	idx := NewTaxIndex([]*Tax{dbTax})
+
+	apiTaxRate := NewTaxRate(TaxTypeCounty, "Los Angeles County", 0.0025)
+	assert.False(t, idx.Contains(apiTaxRate))
+
+	apiTaxRate.AvalaraCode = "037"
+	assert.Equal(t, []*Tax{dbTax}, idx.Candidates(apiTaxRate))
+	assert.True(t, idx.Contains(apiTaxRate))
+}
@@ -0,0 +1,219 @@
+package model
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"unicode"
+)
+
+// MatchResult is the result of comparing a tax saved in DB with a tax rate from the api
+type MatchResult struct {
+	Matched bool `json:"matched"`
+	// Score is the similarity between the tax names, from 0 to 1
+	Score float64 `json:"score"`
+	// Reason explains why the taxes matched or not
+	Reason string `json:"reason"`
+}
+
+// TaxMatcher decides if a tax saved in DB is the same as a tax rate from the api
+type TaxMatcher interface {
+	Match(dbTax *Tax, apiTaxRate *TaxRate) MatchResult
+}
+
+// ExactMatcher matches taxes with the same type, rate and case insensitive name. See IsSameTax
+type ExactMatcher struct{}
+
+// Match compares the taxes using IsSameTax
+func (m ExactMatcher) Match(dbTax *Tax, apiTaxRate *TaxRate) MatchResult {
+	if IsSameTax(dbTax, apiTaxRate) {
+		return MatchResult{Matched: true, Score: 1, Reason: "exact match"}
+	}
+	return MatchResult{Reason: "taxes are different"}
+}
+
+// FuzzyMatcher matches taxes with normalized names, so "Los Angeles County", "LOS ANGELES CO"
+// and "County of Los Angeles" are considered the same tax. When both taxes have jurisdiction
+// codes, they are compared instead of the names.
+type FuzzyMatcher struct {
+	Normalizer *NameNormalizer
+	// RateTolerance is the max absolute difference between two rates that are the same
+	RateTolerance float64
+	// MinScore is the min similarity between normalized names, from 0 to 1, to match taxes
+	MinScore float64
+}
+
+// NewFuzzyMatcher creates a FuzzyMatcher with the default normalization rules
+func NewFuzzyMatcher() *FuzzyMatcher {
+	return &FuzzyMatcher{Normalizer: NewNameNormalizer(), MinScore: 0.9}
+}
+
+// DefaultMatcher is the matcher used by FillTaxParentIds. It's exact, fuzzy matching is chosen
+// with FillTaxParentIdsWithMatcher since it compares every name and is much slower
+var DefaultMatcher TaxMatcher = ExactMatcher{}
+
+// Match compares the type, the rate within the tolerance, and the similarity of the normalized names
+func (m *FuzzyMatcher) Match(dbTax *Tax, apiTaxRate *TaxRate) MatchResult {
+	if !IsSameType(dbTax.Type, apiTaxRate.Type) {
+		return MatchResult{Reason: fmt.Sprintf("type %q is different from %q", dbTax.Type, apiTaxRate.Type)}
+	}
+	if dbTax.Compound != apiTaxRate.Compound {
+		return MatchResult{Reason: "only one of the taxes is compound"}
+	}
+	if math.Abs(dbTax.Rate-apiTaxRate.Rate) > m.RateTolerance {
+		return MatchResult{Reason: fmt.Sprintf("rate %v is different from %v", dbTax.Rate, apiTaxRate.Rate)}
+	}
+	if dbTax.Amount != apiTaxRate.Amount || dbTax.Cap != apiTaxRate.Cap || !sameBrackets(dbTax.Brackets, apiTaxRate.Brackets) {
+		return MatchResult{Reason: "fixed amounts, caps or brackets are different"}
+	}
+
+	if same, known := dbTax.Jurisdiction.IsSame(apiTaxRate.Jurisdiction); known {
+		if same {
+			return MatchResult{Matched: true, Score: 1, Reason: "jurisdiction codes are the same"}
+		}
+		return MatchResult{Reason: "jurisdiction codes are different"}
+	}
+
+	if strings.EqualFold(strings.TrimSpace(dbTax.Name), strings.TrimSpace(apiTaxRate.Name)) {
+		return MatchResult{Matched: true, Score: 1, Reason: "exact match"}
+	}
+
+	normalizer := m.Normalizer
+	if normalizer == nil {
+		normalizer = NewNameNormalizer()
+	}
+	n1 := normalizer.Normalize(dbTax.Name, dbTax.Type)
+	n2 := normalizer.Normalize(apiTaxRate.Name, apiTaxRate.Type)
+	if n1 == n2 {
+		return MatchResult{Matched: true, Score: 1, Reason: fmt.Sprintf("normalized names are the same (%q)", n1)}
+	}
+
+	score := Similarity(n1, n2)
+	if score >= m.MinScore {
+		return MatchResult{Matched: true, Score: score, Reason: fmt.Sprintf("names %q and %q are similar (score %.2f)", n1, n2, score)}
+	}
+	return MatchResult{Score: score, Reason: fmt.Sprintf("names %q and %q are different (score %.2f)", n1, n2, score)}
+}
+
+// NameNormalizer normalizes tax names before comparing them
+type NameNormalizer struct {
+	// Abbreviations maps an abbreviated word to its full form. Example: "co" -> "county"
+	Abbreviations map[string]string
+	// ReversedPrefixes are moved to the end of the name. Example: "county of los angeles" -> "los angeles county"
+	ReversedPrefixes []string
+}
+
+// NewNameNormalizer creates a normalizer with the default rules
+func NewNameNormalizer() *NameNormalizer {
+	return &NameNormalizer{
+		Abbreviations: map[string]string{
+			"co":   "county",
+			"cnty": "county",
+			"cty":  "city",
+			"twp":  "township",
+			"dist": "district",
+			"spl":  "special",
+			"ft":   "fort",
+			"mt":   "mount",
+		},
+		ReversedPrefixes: []string{"county of", "city of", "town of", "village of"},
+	}
+}
+
+// Normalize folds accents and case, removes punctuation, expands abbreviations,
+// reverses prefixes like "County of", and removes the trailing word naming the tax type.
+// Example: "County of Los Angeles" (county) -> "los angeles"
+func (n *NameNormalizer) Normalize(name string, t TaxType) string {
+	words := strings.Fields(foldName(name))
+	for i, w := range words {
+		if full, ok := n.Abbreviations[w]; ok {
+			words[i] = full
+		}
+	}
+
+	normalized := strings.Join(words, " ")
+	for _, prefix := range n.ReversedPrefixes {
+		if strings.HasPrefix(normalized, prefix+" ") {
+			normalized = strings.TrimPrefix(normalized, prefix+" ") + " " + strings.Fields(prefix)[0]
+			break
+		}
+	}
+
+	typeWord := " " + strings.ToLower(strings.TrimSpace(string(t)))
+	if strings.HasSuffix(normalized, typeWord) {
+		normalized = strings.TrimSuffix(normalized, typeWord)
+	}
+	return normalized
+}
+
+// accents maps latin letters with diacritics to their base letter
+var accents = map[rune]rune{
+	'à': 'a', 'á': 'a', 'â': 'a', 'ã': 'a', 'ä': 'a', 'å': 'a',
+	'ç': 'c',
+	'è': 'e', 'é': 'e', 'ê': 'e', 'ë': 'e',
+	'ì': 'i', 'í': 'i', 'î': 'i', 'ï': 'i',
+	'ñ': 'n',
+	'ò': 'o', 'ó': 'o', 'ô': 'o', 'õ': 'o', 'ö': 'o', 'ø': 'o',
+	'ù': 'u', 'ú': 'u', 'û': 'u', 'ü': 'u',
+	'ý': 'y', 'ÿ': 'y',
+}
+
+// foldName lower cases the name, removes the accents and replaces punctuation with spaces
+func foldName(name string) string {
+	return strings.Map(func(r rune) rune {
+		r = unicode.ToLower(r)
+		if base, ok := accents[r]; ok {
+			return base
+		}
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+		if r == '\'' || r == '’' {
+			return -1
+		}
+		return ' '
+	}, name)
+}
+
+// Similarity returns how similar two strings are, from 0 to 1, based on their edit distance
+func Similarity(s1, s2 string) float64 {
+	r1, r2 := []rune(s1), []rune(s2)
+	longest := len(r1)
+	if len(r2) > longest {
+		longest = len(r2)
+	}
+	if longest == 0 {
+		return 1
+	}
+	return 1 - float64(levenshtein(r1, r2))/float64(longest)
+}
+
+func levenshtein(r1, r2 []rune) int {
+	previous := make([]int, len(r2)+1)
+	current := make([]int, len(r2)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(r1); i++ {
+		current[0] = i
+		for j := 1; j <= len(r2); j++ {
+			cost := 1
+			if r1[i-1] == r2[j-1] {
+				cost = 0
+			}
+			current[j] = min3(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(r2)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
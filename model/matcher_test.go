@@ -0,0 +1,110 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNameNormalizer(t *testing.T) {
+	n := NewNameNormalizer()
+
+	assert.Equal(t, "los angeles", n.Normalize("Los Angeles County", TaxTypeCounty))
+	assert.Equal(t, "los angeles", n.Normalize("LOS ANGELES CO", TaxTypeCounty))
+	assert.Equal(t, "los angeles", n.Normalize("County of Los Angeles", TaxTypeCounty))
+	assert.Equal(t, "los angeles county", n.Normalize("County of Los Angeles", TaxTypeSpecial))
+	assert.Equal(t, "montreal", n.Normalize("Montréal", TaxTypeCity))
+	assert.Equal(t, "st louis", n.Normalize("St. Louis", TaxTypeCity))
+	assert.Equal(t, "fort worth", n.Normalize("FT WORTH", TaxTypeCity))
+}
+
+func TestFuzzyMatcher(t *testing.T) {
+	m := NewFuzzyMatcher()
+	apiTaxRate := NewTaxRate(TaxTypeCounty, "Los Angeles County", 0.0025)
+
+	for _, name := range []string{"Los Angeles County", "LOS ANGELES CO", "County of Los Angeles", "Los Angeles"} {
+		result := m.Match(&Tax{Name: name, Rate: 0.0025, Type: TaxTypeCounty}, apiTaxRate)
+		assert.True(t, result.Matched, name)
+		assert.Equal(t, 1.0, result.Score, name)
+		assert.NotEmpty(t, result.Reason, name)
+	}
+
+	result := m.Match(&Tax{Name: "Los Angelos County", Rate: 0.0025, Type: TaxTypeCounty}, apiTaxRate)
+	assert.True(t, result.Matched)
+	assert.True(t, result.Score < 1)
+
+	result = m.Match(&Tax{Name: "Orange County", Rate: 0.0025, Type: TaxTypeCounty}, apiTaxRate)
+	assert.False(t, result.Matched)
+
+	result = m.Match(&Tax{Name: "Los Angeles County", Rate: 0.0025, Type: TaxTypeCity}, apiTaxRate)
+	assert.False(t, result.Matched)
+	assert.Contains(t, result.Reason, "type")
+}
+
+func TestFuzzyMatcherRateTolerance(t *testing.T) {
+	m := NewFuzzyMatcher()
+	apiTaxRate := NewTaxRate(TaxTypeState, "California", 0.0725)
+	dbTax := &Tax{Name: "California", Rate: 0.07249, Type: TaxTypeState}
+
+	result := m.Match(dbTax, apiTaxRate)
+	assert.False(t, result.Matched)
+	assert.Contains(t, result.Reason, "rate")
+
+	m.RateTolerance = 0.0001
+	assert.True(t, m.Match(dbTax, apiTaxRate).Matched)
+}
+
+func TestSimilarity(t *testing.T) {
+	assert.Equal(t, 1.0, Similarity("", ""))
+	assert.Equal(t, 1.0, Similarity("california", "california"))
+	assert.Equal(t, 0.0, Similarity("abc", "xyz"))
+	assert.InDelta(t, 0.9, Similarity("california", "kalifornia"), 0.0000001)
+}
+
+// tests that FillTaxParentIds finds parents with different names and reports the reason
+func TestFillTaxParentIdsWithMatcher(t *testing.T) {
+	tg := TaxGroup{}
+	stateTax := NewTaxRate(TaxTypeState, "New York State", 0.04)
+	tg.AddTaxRate(stateTax)
+
+	dbStateTax := &Tax{Name: "NEW YORK", Rate: 0.04, Type: TaxTypeState, VendTaxID: "myVendTaxId"}
+
+	matches := tg.FillTaxParentIdsWithMatcher([]*Tax{dbStateTax}, ExactMatcher{})
+	assert.Empty(t, matches)
+	assert.Empty(t, stateTax.VendTaxID)
+
+	// FillTaxParentIds keeps matching the exact names
+	tg.FillTaxParentIds([]*Tax{dbStateTax})
+	assert.Empty(t, stateTax.VendTaxID)
+
+	matches = tg.FillTaxParentIdsWithMatcher([]*Tax{dbStateTax}, NewFuzzyMatcher())
+	assert.Len(t, matches, 1)
+	assert.Equal(t, "myVendTaxId", matches[0].VendTaxID)
+	assert.NotEmpty(t, matches[0].Reason)
+	assert.Equal(t, "myVendTaxId", stateTax.VendTaxID)
+}
+
+// tests that the saved tax with the highest score is chosen as parent, not the last one that matches
+func TestFillTaxParentIdsBestMatch(t *testing.T) {
+	tg := TaxGroup{}
+	stateTax := NewTaxRate(TaxTypeState, "California", 0.0725)
+	tg.AddTaxRate(stateTax)
+	matcher := &FuzzyMatcher{MinScore: 0.5}
+
+	matches := tg.FillTaxParentIdsWithMatcher([]*Tax{
+		{Name: "Kalifornia", Rate: 0.0725, Type: TaxTypeState, VendTaxID: "similar"},
+		{Name: "Kalifornja", Rate: 0.0725, Type: TaxTypeState, VendTaxID: "less-similar"},
+	}, matcher)
+	assert.Len(t, matches, 1)
+	assert.Equal(t, "similar", matches[0].VendTaxID)
+	assert.Equal(t, "similar", stateTax.VendTaxID)
+
+	matches = tg.FillTaxParentIdsWithMatcher([]*Tax{
+		{Name: "Kalifornia", Rate: 0.0725, Type: TaxTypeState, VendTaxID: "similar"},
+		{Name: "California", Rate: 0.0725, Type: TaxTypeState, VendTaxID: "exact"},
+		{Name: "CALIFORNIA", Rate: 0.0725, Type: TaxTypeState, VendTaxID: "exact-2"},
+	}, matcher)
+	assert.Len(t, matches, 1)
+	assert.Equal(t, "exact", matches[0].VendTaxID)
+	assert.Equal(t, 1.0, matches[0].Score)
+}
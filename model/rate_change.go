@@ -0,0 +1,92 @@
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+// RateChange represents an announced change of a tax rate in a jurisdiction
+//
+// swagger:model rateChange
+type RateChange struct {
+	Country string  `json:"country"`
+	State   string  `json:"state,omitempty"`
+	Name    string  `json:"name"`
+	Type    TaxType `json:"type"`
+	OldRate float64 `json:"old_rate"`
+	NewRate float64 `json:"new_rate"`
+	// the date when the new rate becomes effective
+	EffectiveDate time.Time `json:"effective_date"`
+	Jurisdiction
+
+	// retailers that have the old rate saved and need to update it before the effective date
+	AffectedRetailers []string `json:"affected_retailers,omitempty"`
+}
+
+// IsIn checks if the change is effective inside the period, and in the country and state.
+// A zero to, or an empty state, means there is no filter for them.
+func (rc *RateChange) IsIn(from, to time.Time, country, state string) bool {
+	if rc.EffectiveDate.Before(from) || (!to.IsZero() && rc.EffectiveDate.After(to)) {
+		return false
+	}
+	if country != "" && NormalizeCountry(rc.Country) != NormalizeCountry(country) {
+		return false
+	}
+	return state == "" || strings.EqualFold(strings.TrimSpace(rc.State), strings.TrimSpace(state))
+}
+
+// Affects checks if a retailer with the saved taxes needs to update them for this change:
+// they have the old rate effective before the change, and no new rate scheduled for it.
+func (rc *RateChange) Affects(taxes []*Tax) bool {
+	before := rc.EffectiveDate.Add(-time.Nanosecond)
+	affected := false
+	for _, tax := range taxes {
+		if !rc.isSameJurisdiction(tax) {
+			continue
+		}
+		if tax.Rate == rc.NewRate && tax.IsActiveAt(rc.EffectiveDate) {
+			return false
+		}
+		if tax.Rate == rc.OldRate && tax.IsActiveAt(before) {
+			affected = true
+		}
+	}
+	return affected
+}
+
+func (rc *RateChange) isSameJurisdiction(tax *Tax) bool {
+	if !IsSameType(rc.Type, tax.Type) {
+		return false
+	}
+	if same, known := rc.Jurisdiction.IsSame(tax.Jurisdiction); known {
+		return same
+	}
+	return strings.EqualFold(strings.TrimSpace(rc.Name), strings.TrimSpace(tax.Name))
+}
+
+// Migrate schedules the new rate in the saved taxes of a retailer affected by the change:
+// the taxes with the old rate end at the effective date, and a copy with the new rate starts on it.
+// It returns the taxes to save, or nothing if the retailer isn't affected.
+func (rc *RateChange) Migrate(taxes []*Tax) []*Tax {
+	if !rc.Affects(taxes) {
+		return nil
+	}
+
+	before := rc.EffectiveDate.Add(-time.Nanosecond)
+	effective := rc.EffectiveDate
+	migrated := []*Tax{}
+	for _, tax := range taxes {
+		if !rc.isSameJurisdiction(tax) || tax.Rate != rc.OldRate || !tax.IsActiveAt(before) {
+			continue
+		}
+		old := *tax
+		old.ValidTo = &effective
+
+		scheduled := *tax
+		scheduled.ID = ""
+		scheduled.Rate = rc.NewRate
+		scheduled.ValidFrom = &effective
+		migrated = append(migrated, &old, &scheduled)
+	}
+	return migrated
+}
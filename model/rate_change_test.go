@@ -0,0 +1,78 @@
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newCaliforniaChange() *RateChange {
+	return &RateChange{
+		Country:       CountryUS,
+		State:         "CA",
+		Name:          "California",
+		Type:          TaxTypeState,
+		OldRate:       0.0725,
+		NewRate:       0.075,
+		EffectiveDate: *date(2018, time.July, 1),
+	}
+}
+
+func TestRateChangeIsIn(t *testing.T) {
+	rc := newCaliforniaChange()
+
+	assert.True(t, rc.IsIn(*date(2018, time.January, 1), time.Time{}, "", ""))
+	assert.True(t, rc.IsIn(*date(2018, time.January, 1), *date(2018, time.December, 31), "us", "ca"))
+	assert.False(t, rc.IsIn(*date(2018, time.July, 2), time.Time{}, "", ""))
+	assert.False(t, rc.IsIn(*date(2018, time.January, 1), *date(2018, time.June, 30), "", ""))
+	assert.False(t, rc.IsIn(*date(2018, time.January, 1), time.Time{}, "", "NY"))
+	assert.False(t, rc.IsIn(*date(2018, time.January, 1), time.Time{}, "CA", ""))
+}
+
+func TestRateChangeAffects(t *testing.T) {
+	rc := newCaliforniaChange()
+	current := &Tax{Name: "california", Rate: 0.0725, Type: TaxTypeState}
+	city := &Tax{Name: "Santa Monica", Rate: 0.01, Type: TaxTypeCity}
+
+	assert.True(t, rc.Affects([]*Tax{current, city}))
+	assert.False(t, rc.Affects([]*Tax{city}))
+
+	// the retailer already scheduled the new rate
+	current.ValidTo = &rc.EffectiveDate
+	scheduled := &Tax{Name: "California", Rate: 0.075, Type: TaxTypeState}
+	scheduled.ValidFrom = &rc.EffectiveDate
+	assert.False(t, rc.Affects([]*Tax{current, scheduled, city}))
+}
+
+// tests that the jurisdiction codes are preferred over the name
+func TestRateChangeAffectsJurisdiction(t *testing.T) {
+	rc := newCaliforniaChange()
+	rc.FIPSCode = "06"
+
+	assert.True(t, rc.Affects([]*Tax{{Name: "CA State", Rate: 0.0725, Type: TaxTypeState, Jurisdiction: Jurisdiction{FIPSCode: "06"}}}))
+	assert.False(t, rc.Affects([]*Tax{{Name: "California", Rate: 0.0725, Type: TaxTypeState, Jurisdiction: Jurisdiction{FIPSCode: "36"}}}))
+}
+
+func TestRateChangeMigrate(t *testing.T) {
+	rc := newCaliforniaChange()
+	current := &Tax{ID: "tax-1", RetailerID: "retailer-1", Name: "California", Rate: 0.0725, Type: TaxTypeState}
+	city := &Tax{ID: "tax-2", RetailerID: "retailer-1", Name: "Santa Monica", Rate: 0.01, Type: TaxTypeCity}
+
+	migrated := rc.Migrate([]*Tax{current, city})
+	assert.Len(t, migrated, 2)
+
+	old, scheduled := migrated[0], migrated[1]
+	assert.Equal(t, "tax-1", old.ID)
+	assert.Equal(t, rc.EffectiveDate, *old.ValidTo)
+	assert.Nil(t, current.ValidTo)
+	assert.Empty(t, scheduled.ID)
+	assert.Equal(t, "retailer-1", scheduled.RetailerID)
+	assert.Equal(t, 0.075, scheduled.Rate)
+	assert.Equal(t, rc.EffectiveDate, *scheduled.ValidFrom)
+	assert.Nil(t, scheduled.ValidTo)
+
+	// once migrated, the retailer isn't affected anymore
+	assert.Empty(t, rc.Migrate(append(migrated, city)))
+	assert.Empty(t, rc.Migrate([]*Tax{city}))
+}
@@ -0,0 +1,60 @@
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func date(year int, month time.Month, day int) *time.Time {
+	t := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	return &t
+}
+
+func TestValidityIsActiveAt(t *testing.T) {
+	v := Validity{}
+	assert.True(t, v.IsActiveAt(time.Now()))
+
+	v = Validity{ValidFrom: date(2018, time.January, 1), ValidTo: date(2018, time.July, 1)}
+	assert.False(t, v.IsActiveAt(*date(2017, time.December, 31)))
+	assert.True(t, v.IsActiveAt(*date(2018, time.January, 1)))
+	assert.True(t, v.IsActiveAt(*date(2018, time.June, 30)))
+	assert.False(t, v.IsActiveAt(*date(2018, time.July, 1)))
+}
+
+// tests that a scheduled rate replaces the current one on its ValidFrom date
+func TestTaxGroupActiveAt(t *testing.T) {
+	current := NewTaxRate(TaxTypeState, "California", 0.0725)
+	current.ValidTo = date(2018, time.July, 1)
+	scheduled := NewTaxRate(TaxTypeState, "California", 0.075)
+	scheduled.ValidFrom = date(2018, time.July, 1)
+
+	tg := TaxGroup{}
+	tg.AddTaxRate(current)
+	tg.AddTaxRate(scheduled)
+
+	before := tg.ActiveAt(*date(2018, time.June, 30))
+	assert.Len(t, before.Rates, 1)
+	assert.InDelta(t, 0.0725, before.TotalRate, 0.0000001)
+
+	after := tg.ActiveAt(*date(2018, time.July, 1))
+	assert.Len(t, after.Rates, 1)
+	assert.InDelta(t, 0.075, after.TotalRate, 0.0000001)
+}
+
+func TestActiveTaxes(t *testing.T) {
+	current := &Tax{Name: "California", Rate: 0.0725, Type: TaxTypeState, VendTaxID: "current"}
+	current.ValidTo = date(2018, time.July, 1)
+	scheduled := &Tax{Name: "California", Rate: 0.075, Type: TaxTypeState, VendTaxID: "scheduled"}
+	scheduled.ValidFrom = date(2018, time.July, 1)
+	taxes := []*Tax{current, scheduled}
+
+	active := ActiveTaxes(taxes, *date(2018, time.January, 1))
+	assert.Len(t, active, 1)
+	assert.Equal(t, "current", active[0].VendTaxID)
+
+	active = ActiveTaxes(taxes, *date(2019, time.January, 1))
+	assert.Len(t, active, 1)
+	assert.Equal(t, "scheduled", active[0].VendTaxID)
+}
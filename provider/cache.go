@@ -0,0 +1,172 @@
+package provider
+
+import (
+	"container/list"
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/renanrt/lab-go-api/address"
+	"github.com/renanrt/lab-go-api/geo"
+	"github.com/renanrt/lab-go-api/model"
+)
+
+// Cached is a provider that keeps the candidates returned by another provider for a while,
+// so repeated lookups of the same address don't reach the api. The addresses come from the
+// clients, so the number of entries is limited: the least recently used ones are removed first.
+type Cached struct {
+	inner      Provider
+	ttl        time.Duration
+	maxEntries int
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	// lru has the entries from the most to the least recently used
+	lru *list.List
+
+	// now is replaced in tests
+	now func() time.Time
+}
+
+type cacheEntry struct {
+	key        string
+	candidates []*model.TaxGroup
+	expires    time.Time
+}
+
+// NewCached creates a provider that caches the results of inner for the ttl, keeping at most maxEntries
+func NewCached(inner Provider, ttl time.Duration, maxEntries int) *Cached {
+	return &Cached{inner: inner, ttl: ttl, maxEntries: maxEntries, entries: map[string]*list.Element{}, lru: list.New(), now: time.Now}
+}
+
+// Name returns the name of the inner provider
+func (c *Cached) Name() string {
+	return c.inner.Name()
+}
+
+// GetTaxes returns the candidate with the highest confidence
+func (c *Cached) GetTaxes(ctx context.Context, addr address.Address) (*model.TaxGroup, error) {
+	candidates, err := c.GetTaxCandidates(ctx, addr)
+	if err != nil {
+		return nil, err
+	}
+	return candidates[0], nil
+}
+
+// GetTaxCandidates returns copies of the cached candidates, or looks them up in the inner provider.
+// Errors are not cached, so a cancelled lookup is tried again by the next request.
+func (c *Cached) GetTaxCandidates(ctx context.Context, addr address.Address) ([]*model.TaxGroup, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	key := strings.ToUpper(strings.Join([]string{addr.Country, addr.State, addr.City, addr.Zipcode, addr.Street}, "|"))
+
+	if candidates, ok := c.get(key); ok {
+		return candidates, nil
+	}
+
+	candidates, err := getCandidates(ctx, c.inner, addr)
+	if err != nil {
+		return nil, err
+	}
+	c.set(key, candidates)
+	return candidates, nil
+}
+
+// get returns copies of the candidates of the key, if they didn't expire
+func (c *Cached) get(key string) ([]*model.TaxGroup, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := element.Value.(*cacheEntry)
+	if !c.now().Before(entry.expires) {
+		c.remove(element)
+		return nil, false
+	}
+	c.lru.MoveToFront(element)
+	return model.CopyTaxGroups(entry.candidates), true
+}
+
+// set caches copies of the candidates, removing the expired entries at the end of the list
+// and then the least recently used ones over the limit
+func (c *Cached) set(key string, candidates []*model.TaxGroup) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	entry := &cacheEntry{key: key, candidates: model.CopyTaxGroups(candidates), expires: now.Add(c.ttl)}
+	if element, ok := c.entries[key]; ok {
+		element.Value = entry
+		c.lru.MoveToFront(element)
+	} else {
+		c.entries[key] = c.lru.PushFront(entry)
+	}
+
+	for back := c.lru.Back(); back != nil && !now.Before(back.Value.(*cacheEntry).expires); back = c.lru.Back() {
+		c.remove(back)
+	}
+	for c.maxEntries > 0 && c.lru.Len() > c.maxEntries {
+		c.remove(c.lru.Back())
+	}
+}
+
+func (c *Cached) remove(element *list.Element) {
+	c.lru.Remove(element)
+	delete(c.entries, element.Value.(*cacheEntry).key)
+}
+
+// GetTaxesForPoint calls the inner provider, the points are not cached
+func (c *Cached) GetTaxesForPoint(ctx context.Context, country string, p geo.Point) ([]*model.TaxGroup, error) {
+	return getTaxesForPoint(ctx, c.inner, country, p)
+}
+
+// Purge removes all cached entries, returning how many were removed
+func (c *Cached) Purge() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	n := len(c.entries)
+	c.entries = map[string]*list.Element{}
+	c.lru.Init()
+	return n
+}
+
+// Len returns the number of cached entries
+func (c *Cached) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+// RateChanges returns the rate changes of the inner provider, if it knows them
+func (c *Cached) RateChanges() []*model.RateChange {
+	if source, ok := c.inner.(RateChangeSource); ok {
+		return source.RateChanges()
+	}
+	return nil
+}
+
+// getCandidates returns all candidates of a CandidateProvider, or the taxes of any other provider
+func getCandidates(ctx context.Context, p Provider, addr address.Address) ([]*model.TaxGroup, error) {
+	if candidateProvider, ok := p.(CandidateProvider); ok {
+		return candidateProvider.GetTaxCandidates(ctx, addr)
+	}
+	taxGroup, err := p.GetTaxes(ctx, addr)
+	if err != nil {
+		return nil, err
+	}
+	return []*model.TaxGroup{taxGroup}, nil
+}
+
+// getTaxesForPoint returns the taxes of a CoordinateProvider, or ErrPointNotSupported for any other provider
+func getTaxesForPoint(ctx context.Context, p Provider, country string, point geo.Point) ([]*model.TaxGroup, error) {
+	if coordinateProvider, ok := p.(CoordinateProvider); ok {
+		return coordinateProvider.GetTaxesForPoint(ctx, country, point)
+	}
+	return nil, ErrPointNotSupported
+}
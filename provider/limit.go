@@ -0,0 +1,100 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/renanrt/lab-go-api/address"
+	"github.com/renanrt/lab-go-api/apperrors"
+	"github.com/renanrt/lab-go-api/geo"
+	"github.com/renanrt/lab-go-api/model"
+)
+
+// RateLimited is a provider that limits the number of requests sent to another provider,
+// so concurrent lookups respect the limits of the provider api.
+type RateLimited struct {
+	inner    Provider
+	interval time.Duration
+
+	mu   sync.Mutex
+	next time.Time
+}
+
+// NewRateLimited creates a provider that sends at most perSecond requests to inner.
+// A perSecond of 0 or less is unlimited, like the ProviderRateLimit of the configuration.
+func NewRateLimited(inner Provider, perSecond int) *RateLimited {
+	l := &RateLimited{inner: inner}
+	if perSecond > 0 {
+		l.interval = time.Second / time.Duration(perSecond)
+	}
+	return l
+}
+
+// Name returns the name of the inner provider
+func (l *RateLimited) Name() string {
+	return l.inner.Name()
+}
+
+// GetTaxes waits for its turn and calls the inner provider
+func (l *RateLimited) GetTaxes(ctx context.Context, addr address.Address) (*model.TaxGroup, error) {
+	if err := l.wait(ctx); err != nil {
+		return nil, err
+	}
+	return l.inner.GetTaxes(ctx, addr)
+}
+
+// GetTaxCandidates waits for its turn and calls the inner provider
+func (l *RateLimited) GetTaxCandidates(ctx context.Context, addr address.Address) ([]*model.TaxGroup, error) {
+	if err := l.wait(ctx); err != nil {
+		return nil, err
+	}
+	return getCandidates(ctx, l.inner, addr)
+}
+
+// GetTaxesForPoint waits for its turn and calls the inner provider, if it supports points
+func (l *RateLimited) GetTaxesForPoint(ctx context.Context, country string, p geo.Point) ([]*model.TaxGroup, error) {
+	if _, ok := l.inner.(CoordinateProvider); !ok {
+		return nil, ErrPointNotSupported
+	}
+	if err := l.wait(ctx); err != nil {
+		return nil, err
+	}
+	return getTaxesForPoint(ctx, l.inner, country, p)
+}
+
+// RateChanges returns the rate changes of the inner provider, if it knows them
+func (l *RateLimited) RateChanges() []*model.RateChange {
+	if source, ok := l.inner.(RateChangeSource); ok {
+		return source.RateChanges()
+	}
+	return nil
+}
+
+// wait blocks until the interval since the previous request has passed, or returns the
+// error of the context if it's done first. When the turn is after the deadline of the
+// context, it returns a rate limited error without waiting.
+func (l *RateLimited) wait(ctx context.Context) error {
+	l.mu.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	turn := l.next
+	if deadline, ok := ctx.Deadline(); ok && turn.After(deadline) {
+		l.mu.Unlock()
+		return apperrors.RateLimited(fmt.Sprintf("too many requests to provider %s", l.Name()), turn.Sub(now))
+	}
+	l.next = l.next.Add(l.interval)
+	l.mu.Unlock()
+
+	timer := time.NewTimer(turn.Sub(now))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
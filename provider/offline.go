@@ -0,0 +1,245 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/renanrt/lab-go-api/address"
+	"github.com/renanrt/lab-go-api/apperrors"
+	"github.com/renanrt/lab-go-api/model"
+)
+
+// Offline is a provider that resolves taxes from a static table, without calling any api.
+// It's used for development, tests, and as a fallback when the external providers are down.
+type Offline struct {
+	// rates by country and state. The empty state holds the country wide taxes
+	rates map[string]map[string][]*model.TaxRate
+	// localities by US zipcode. A zipcode can span several cities or counties
+	localities map[string][]*locality
+	// announced rate changes
+	changes []*model.RateChange
+}
+
+// locality is a part of a zipcode with its own local taxes
+type locality struct {
+	State  string
+	County string
+	City   string
+	// Share is the portion of the addresses in the zipcode that belong to this locality
+	Share float64
+	Rates []*model.TaxRate
+}
+
+// NewOffline creates an offline provider with the built in rates
+func NewOffline() *Offline {
+	return &Offline{rates: defaultRates(), localities: defaultLocalities(), changes: defaultRateChanges()}
+}
+
+// Name returns the offline provider name
+func (o *Offline) Name() string {
+	return model.OFFLINE
+}
+
+// GetTaxes returns a copy of the taxes for the address.
+// When the zipcode is ambiguous, the candidate with the highest confidence is returned.
+func (o *Offline) GetTaxes(ctx context.Context, addr address.Address) (*model.TaxGroup, error) {
+	candidates, err := o.GetTaxCandidates(ctx, addr)
+	if err != nil {
+		return nil, err
+	}
+	return candidates[0], nil
+}
+
+// GetTaxCandidates returns copies of the taxes for every locality of the zipcode that matches
+// the city, sorted by confidence. Zipcodes that are not in the table use the state taxes.
+func (o *Offline) GetTaxCandidates(ctx context.Context, addr address.Address) ([]*model.TaxGroup, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	country := model.NormalizeCountry(addr.Country)
+	states, ok := o.rates[country]
+	if !ok {
+		return nil, apperrors.New(apperrors.CodeNoTaxesFound, fmt.Sprintf("no taxes found for country %s", country))
+	}
+
+	localities := []*locality{}
+	if country == model.CountryUS {
+		localities = filterLocalities(o.localities[strings.TrimSpace(addr.Zipcode)], addr.State, addr.City)
+	}
+	if len(localities) == 0 {
+		localities = []*locality{{State: addr.State, Share: 1}}
+	}
+
+	total := 0.0
+	for _, l := range localities {
+		total += l.Share
+	}
+
+	candidates := []*model.TaxGroup{}
+	for _, l := range localities {
+		rates := states[""]
+		if len(rates) == 0 {
+			rates, ok = states[strings.ToUpper(strings.TrimSpace(l.State))]
+			if !ok {
+				return nil, apperrors.New(apperrors.CodeNoTaxesFound, fmt.Sprintf("no taxes found for state %q in %s", l.State, country))
+			}
+		}
+
+		taxGroup := &model.TaxGroup{Confidence: l.Share / total}
+		for _, tr := range rates {
+			rate := *tr
+			taxGroup.AddTaxRate(&rate)
+		}
+		for _, tr := range l.Rates {
+			rate := *tr
+			taxGroup.AddTaxRate(&rate)
+		}
+		if len(localities) > 1 {
+			taxGroup.Jurisdictions = l.jurisdictions()
+		}
+		taxGroup.UpdateTotalRate()
+		candidates = append(candidates, taxGroup)
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].Confidence > candidates[j].Confidence
+	})
+	return candidates, nil
+}
+
+// filterLocalities returns the localities in the state and city. When none of them is
+// in the city, the city is ignored, since addresses often use a neighbouring city name.
+func filterLocalities(localities []*locality, state, city string) []*locality {
+	inState := []*locality{}
+	for _, l := range localities {
+		if state == "" || strings.EqualFold(l.State, strings.TrimSpace(state)) {
+			inState = append(inState, l)
+		}
+	}
+
+	inCity := []*locality{}
+	for _, l := range inState {
+		if city != "" && strings.EqualFold(l.City, strings.TrimSpace(city)) {
+			inCity = append(inCity, l)
+		}
+	}
+	if len(inCity) > 0 {
+		return inCity
+	}
+	return inState
+}
+
+// jurisdictions returns the names that distinguish the locality from the others in the zipcode
+func (l *locality) jurisdictions() []string {
+	jurisdictions := []string{}
+	if l.City != "" {
+		jurisdictions = append(jurisdictions, l.City)
+	}
+	if l.County != "" {
+		jurisdictions = append(jurisdictions, l.County)
+	}
+	return jurisdictions
+}
+
+// RateChanges returns copies of the announced rate changes
+func (o *Offline) RateChanges() []*model.RateChange {
+	changes := []*model.RateChange{}
+	for _, rc := range o.changes {
+		change := *rc
+		changes = append(changes, &change)
+	}
+	return changes
+}
+
+func defaultRates() map[string]map[string][]*model.TaxRate {
+	gst := model.NewTaxRate(model.TaxTypeGST, "GST", 0.05)
+	rates := map[string]map[string][]*model.TaxRate{
+		model.CountryUS: {
+			"CA": {stateTaxRate("California", "06", 0.0725)},
+			"NY": {stateTaxRate("New York", "36", 0.04)},
+			"TN": {stateTaxRate("Tennessee", "47", 0.07)},
+			"WA": {stateTaxRate("Washington", "53", 0.065)},
+		},
+		model.CountryCA: {
+			"AB": {gst},
+			"BC": {gst, model.NewTaxRate(model.TaxTypePST, "British Columbia PST", 0.07)},
+			"MB": {gst, model.NewTaxRate(model.TaxTypePST, "Manitoba RST", 0.07)},
+			"ON": {model.NewTaxRate(model.TaxTypeHST, "Ontario HST", 0.13)},
+			"NS": {model.NewTaxRate(model.TaxTypeHST, "Nova Scotia HST", 0.15)},
+			"QC": {gst, model.NewTaxRate(model.TaxTypeQST, "QST", 0.09975)},
+		},
+		model.CountryAU: {"": {model.NewTaxRate(model.TaxTypeGST, "GST", 0.10)}},
+		model.CountryNZ: {"": {model.NewTaxRate(model.TaxTypeGST, "GST", 0.15)}},
+		model.CountryGB: {"": {model.NewTaxRate(model.TaxTypeVAT, "VAT", 0.20)}},
+	}
+
+	vat := map[string]float64{"DE": 0.19, "ES": 0.21, "FR": 0.20, "IE": 0.23, "IT": 0.22, "NL": 0.21}
+	for country, rate := range vat {
+		rates[country] = map[string][]*model.TaxRate{"": {model.NewTaxRate(model.TaxTypeVAT, "VAT", rate)}}
+	}
+	return rates
+}
+
+func defaultLocalities() map[string][]*locality {
+	laCounty := model.NewTaxRate(model.TaxTypeCounty, "Los Angeles County", 0.0225)
+	laCounty.FIPSCode = "06037"
+
+	return map[string][]*locality{
+		"90046": {
+			{State: "CA", County: "Los Angeles County", City: "Los Angeles", Share: 0.6, Rates: []*model.TaxRate{laCounty}},
+			{State: "CA", County: "Los Angeles County", City: "West Hollywood", Share: 0.4, Rates: []*model.TaxRate{
+				laCounty,
+				model.NewTaxRate(model.TaxTypeCity, "West Hollywood", 0.0075),
+			}},
+		},
+		"90401": {
+			{State: "CA", County: "Los Angeles County", City: "Santa Monica", Share: 1, Rates: []*model.TaxRate{
+				laCounty,
+				model.NewTaxRate(model.TaxTypeCity, "Santa Monica", 0.01),
+			}},
+		},
+		"37027": {
+			{State: "TN", County: "Williamson County", City: "Brentwood", Share: 0.8, Rates: []*model.TaxRate{
+				model.NewTaxRate(model.TaxTypeCounty, "Williamson County", 0.0225),
+			}},
+			{State: "TN", County: "Davidson County", City: "Nashville", Share: 0.2, Rates: []*model.TaxRate{
+				model.NewTaxRate(model.TaxTypeCounty, "Davidson County", 0.0225),
+			}},
+		},
+	}
+}
+
+func defaultRateChanges() []*model.RateChange {
+	return []*model.RateChange{
+		{
+			Country:       model.CountryUS,
+			State:         "TN",
+			Name:          "Tennessee",
+			Type:          model.TaxTypeState,
+			OldRate:       0.07,
+			NewRate:       0.0725,
+			EffectiveDate: time.Date(2027, time.January, 1, 0, 0, 0, 0, time.UTC),
+			Jurisdiction:  model.Jurisdiction{FIPSCode: "47"},
+		},
+		{
+			Country:       model.CountryCA,
+			State:         "NS",
+			Name:          "Nova Scotia HST",
+			Type:          model.TaxTypeHST,
+			OldRate:       0.15,
+			NewRate:       0.14,
+			EffectiveDate: time.Date(2027, time.April, 1, 0, 0, 0, 0, time.UTC),
+		},
+	}
+}
+
+// stateTaxRate creates a US state tax rate with its FIPS code
+func stateTaxRate(name, fipsCode string, rate float64) *model.TaxRate {
+	tr := model.NewTaxRate(model.TaxTypeState, name, rate)
+	tr.FIPSCode = fipsCode
+	return tr
+}
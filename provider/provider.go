@@ -0,0 +1,153 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/renanrt/lab-go-api/address"
+	"github.com/renanrt/lab-go-api/apperrors"
+	"github.com/renanrt/lab-go-api/geo"
+	"github.com/renanrt/lab-go-api/model"
+)
+
+// Provider represents a tax api provider (Avalara, TaxJar...) that finds the taxes for an address
+type Provider interface {
+	// Name returns the name used to select the provider in a request
+	Name() string
+	// GetTaxes returns the taxes for an address. The lookup stops when the context is done
+	GetTaxes(ctx context.Context, addr address.Address) (*model.TaxGroup, error)
+}
+
+// CandidateProvider is implemented by providers that can return every jurisdiction of an
+// ambiguous address, like a zipcode that spans several cities or counties
+type CandidateProvider interface {
+	// GetTaxCandidates returns the taxes of each jurisdiction, sorted by confidence
+	GetTaxCandidates(ctx context.Context, addr address.Address) ([]*model.TaxGroup, error)
+}
+
+// CoordinateProvider is implemented by providers that can find the taxes for a GPS position
+// directly, without geocoding it to an address first
+type CoordinateProvider interface {
+	// GetTaxesForPoint returns the taxes of each jurisdiction that contains the point, sorted by confidence.
+	// Wrappers like Cached return ErrPointNotSupported when the provider they wrap doesn't support points.
+	GetTaxesForPoint(ctx context.Context, country string, p geo.Point) ([]*model.TaxGroup, error)
+}
+
+// ErrPointNotSupported is returned by GetTaxesForPoint when the provider can't search by coordinates
+var ErrPointNotSupported = errors.New("the provider doesn't support coordinates")
+
+// RateChangeSource is implemented by providers that know the announced rate changes
+type RateChangeSource interface {
+	RateChanges() []*model.RateChange
+}
+
+// Purger is implemented by providers that cache their results
+type Purger interface {
+	// Purge removes all cached entries, returning how many were removed
+	Purge() int
+	// Len returns the number of cached entries
+	Len() int
+}
+
+// Status describes a provider of the registry, for the admins
+type Status struct {
+	Name         string `json:"name"`
+	Default      bool   `json:"default"`
+	Cached       bool   `json:"cached"`
+	CacheEntries int    `json:"cache_entries"`
+	RateChanges  int    `json:"rate_changes"`
+}
+
+// Registry keeps the providers available to the service
+type Registry struct {
+	providers   map[string]Provider
+	defaultName string
+}
+
+// NewRegistry creates a registry with the supplied providers.
+// The first provider is used when a request doesn't choose one.
+func NewRegistry(providers ...Provider) *Registry {
+	r := &Registry{providers: map[string]Provider{}}
+	for _, p := range providers {
+		r.Register(p)
+	}
+	return r
+}
+
+// Register adds a provider to the registry
+func (r *Registry) Register(p Provider) {
+	name := normalizeName(p.Name())
+	if r.defaultName == "" {
+		r.defaultName = name
+	}
+	r.providers[name] = p
+}
+
+// Get returns the provider by name, or the default provider if the name is empty
+func (r *Registry) Get(name string) (Provider, error) {
+	name = normalizeName(name)
+	if name == "" {
+		name = r.defaultName
+	}
+	p, ok := r.providers[name]
+	if !ok {
+		return nil, &apperrors.Error{
+			Code:    apperrors.CodeUnknownProvider,
+			Message: fmt.Sprintf("provider %q is not available", name),
+			Fields:  map[string]string{"provider": "is not available"},
+		}
+	}
+	return p, nil
+}
+
+// RateChanges returns the announced rate changes of all providers that know them
+func (r *Registry) RateChanges() []*model.RateChange {
+	changes := []*model.RateChange{}
+	for _, p := range r.providers {
+		if source, ok := p.(RateChangeSource); ok {
+			changes = append(changes, source.RateChanges()...)
+		}
+	}
+	return changes
+}
+
+// Purge removes the cached entries of all providers, returning how many were removed
+func (r *Registry) Purge() int {
+	n := 0
+	for _, p := range r.providers {
+		if purger, ok := p.(Purger); ok {
+			n += purger.Purge()
+		}
+	}
+	return n
+}
+
+// Status returns the status of each provider, sorted by name
+func (r *Registry) Status() []*Status {
+	statuses := []*Status{}
+	for name, p := range r.providers {
+		status := &Status{Name: name, Default: name == r.defaultName}
+		if purger, ok := p.(Purger); ok {
+			status.Cached = true
+			status.CacheEntries = purger.Len()
+		}
+		if source, ok := p.(RateChangeSource); ok {
+			status.RateChanges = len(source.RateChanges())
+		}
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
+
+// DefaultRegistry returns a registry with the offline provider
+func DefaultRegistry() *Registry {
+	return NewRegistry(NewOffline())
+}
+
+func normalizeName(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
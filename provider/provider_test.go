@@ -0,0 +1,230 @@
+package provider
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/renanrt/lab-go-api/address"
+	"github.com/renanrt/lab-go-api/apperrors"
+	"github.com/renanrt/lab-go-api/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistryGet(t *testing.T) {
+	r := DefaultRegistry()
+
+	p, err := r.Get("")
+	assert.NoError(t, err)
+	assert.Equal(t, model.OFFLINE, p.Name())
+
+	p, err = r.Get(" Offline ")
+	assert.NoError(t, err)
+	assert.Equal(t, model.OFFLINE, p.Name())
+
+	_, err = r.Get("unknown")
+	assert.Equal(t, apperrors.CodeUnknownProvider, err.(*apperrors.Error).Code)
+}
+
+func TestOfflineGetTaxes(t *testing.T) {
+	o := NewOffline()
+
+	tg, err := o.GetTaxes(context.Background(), testAddress("CA", "bc", "Vancouver", "V6B 1A1", ""))
+	assert.NoError(t, err)
+	assert.Len(t, tg.Rates, 2)
+	assert.InDelta(t, 0.12, tg.TotalRate, 0.0000001)
+	assert.NoError(t, tg.Validate("CA"))
+
+	tg, err = o.GetTaxes(context.Background(), testAddress("nz", "", "Auckland", "1010", ""))
+	assert.NoError(t, err)
+	assert.Len(t, tg.GetTaxByType(model.TaxTypeGST), 1)
+
+	tg, err = o.GetTaxes(context.Background(), testAddress("DE", "", "Berlin", "10115", ""))
+	assert.NoError(t, err)
+	assert.Len(t, tg.GetTaxByType(model.TaxTypeVAT), 1)
+
+	_, err = o.GetTaxes(context.Background(), testAddress("US", "ZZ", "", "90401", ""))
+	assert.Error(t, err)
+}
+
+// tests that the rates returned can be modified without changing the provider data
+func TestOfflineReturnsCopies(t *testing.T) {
+	o := NewOffline()
+
+	tg, _ := o.GetTaxes(context.Background(), testAddress("US", "CA", "", "90401", ""))
+	tg.Rates[0].VendTaxID = "vend-tax-id"
+
+	tg, _ = o.GetTaxes(context.Background(), testAddress("US", "CA", "", "90401", ""))
+	assert.Empty(t, tg.Rates[0].VendTaxID)
+}
+
+// tests that a zipcode spanning several cities returns every candidate
+func TestOfflineGetTaxCandidates(t *testing.T) {
+	o := NewOffline()
+
+	candidates, err := o.GetTaxCandidates(context.Background(), testAddress("US", "", "", "90046", ""))
+	assert.NoError(t, err)
+	assert.Len(t, candidates, 2)
+	assert.Equal(t, []string{"Los Angeles", "Los Angeles County"}, candidates[0].Jurisdictions)
+	assert.InDelta(t, 0.6, candidates[0].Confidence, 0.0000001)
+	assert.InDelta(t, 0.095, candidates[0].TotalRate, 0.0000001)
+	assert.Equal(t, []string{"West Hollywood", "Los Angeles County"}, candidates[1].Jurisdictions)
+	assert.InDelta(t, 0.4, candidates[1].Confidence, 0.0000001)
+	assert.InDelta(t, 0.1025, candidates[1].TotalRate, 0.0000001)
+
+	// the city disambiguates the zipcode
+	candidates, err = o.GetTaxCandidates(context.Background(), testAddress("US", "CA", "west hollywood", "90046", ""))
+	assert.NoError(t, err)
+	assert.Len(t, candidates, 1)
+	assert.Equal(t, 1.0, candidates[0].Confidence)
+	assert.Empty(t, candidates[0].Jurisdictions)
+
+	tg, err := o.GetTaxes(context.Background(), testAddress("US", "", "", "90046", ""))
+	assert.NoError(t, err)
+	assert.Equal(t, candidates[0].Rates[0].Name, tg.Rates[0].Name)
+	assert.Len(t, tg.Rates, 2)
+}
+
+func testAddress(country, state, city, zipcode, street string) address.Address {
+	return address.Address{Country: country, State: state, City: city, Zipcode: zipcode, Street: street}
+}
+
+// countingProvider counts the lookups sent to it
+type countingProvider struct {
+	calls int32
+}
+
+func (p *countingProvider) Name() string {
+	return "counting"
+}
+
+func (p *countingProvider) GetTaxes(ctx context.Context, addr address.Address) (*model.TaxGroup, error) {
+	atomic.AddInt32(&p.calls, 1)
+	taxGroup := &model.TaxGroup{}
+	taxGroup.AddTaxRate(model.NewTaxRate(model.TaxTypeState, "California", 0.0725))
+	return taxGroup, nil
+}
+
+func TestCached(t *testing.T) {
+	inner := &countingProvider{}
+	c := NewCached(inner, time.Minute, 100)
+	assert.Equal(t, "counting", c.Name())
+
+	tg, err := c.GetTaxes(context.Background(), testAddress("US", "CA", "", "90401", ""))
+	assert.NoError(t, err)
+	tg.Rates[0].VendTaxID = "vend-tax-id"
+
+	candidates, err := c.GetTaxCandidates(context.Background(), testAddress("us", "ca", "", "90401", ""))
+	assert.NoError(t, err)
+	assert.Len(t, candidates, 1)
+	assert.Empty(t, candidates[0].Rates[0].VendTaxID)
+	assert.Equal(t, int32(1), inner.calls)
+
+	c.GetTaxes(context.Background(), testAddress("US", "NY", "", "10001", ""))
+	assert.Equal(t, int32(2), inner.calls)
+
+	// cancelled lookups don't reach the provider, even for cached addresses
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = c.GetTaxes(ctx, testAddress("US", "CA", "", "90401", ""))
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, int32(2), inner.calls)
+}
+
+// tests that the expired and the least recently used entries are removed
+func TestCachedLimits(t *testing.T) {
+	now := time.Date(2018, time.July, 1, 10, 0, 0, 0, time.UTC)
+	inner := &countingProvider{}
+	c := NewCached(inner, time.Minute, 2)
+	c.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	c.GetTaxes(ctx, testAddress("US", "CA", "", "90401", ""))
+	c.GetTaxes(ctx, testAddress("US", "NY", "", "10001", ""))
+	c.GetTaxes(ctx, testAddress("US", "CA", "", "90401", ""))
+	c.GetTaxes(ctx, testAddress("US", "TX", "", "73301", ""))
+	assert.Equal(t, 2, c.Len())
+	assert.Equal(t, int32(3), inner.calls)
+
+	// NY was the least recently used
+	c.GetTaxes(ctx, testAddress("US", "CA", "", "90401", ""))
+	assert.Equal(t, int32(3), inner.calls)
+	c.GetTaxes(ctx, testAddress("US", "NY", "", "10001", ""))
+	assert.Equal(t, int32(4), inner.calls)
+
+	// the expired entries are removed when new ones are added
+	now = now.Add(2 * time.Minute)
+	c.GetTaxes(ctx, testAddress("US", "WA", "", "98101", ""))
+	assert.Equal(t, 1, c.Len())
+}
+
+func TestRegistryPurgeAndStatus(t *testing.T) {
+	c := NewCached(&countingProvider{}, time.Minute, 100)
+	r := NewRegistry(NewOffline(), c)
+	c.GetTaxes(context.Background(), testAddress("US", "CA", "", "90401", ""))
+	c.GetTaxes(context.Background(), testAddress("US", "NY", "", "10001", ""))
+
+	statuses := r.Status()
+	assert.Len(t, statuses, 2)
+	assert.Equal(t, &Status{Name: "counting", Cached: true, CacheEntries: 2}, statuses[0])
+	assert.Equal(t, model.OFFLINE, statuses[1].Name)
+	assert.True(t, statuses[1].Default)
+	assert.False(t, statuses[1].Cached)
+	assert.NotZero(t, statuses[1].RateChanges)
+
+	assert.Equal(t, 2, r.Purge())
+	assert.Equal(t, 0, c.Len())
+}
+
+func TestRateLimited(t *testing.T) {
+	inner := &countingProvider{}
+	l := NewRateLimited(inner, 100)
+
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		_, err := l.GetTaxCandidates(context.Background(), testAddress("US", "CA", "", "90401", ""))
+		assert.NoError(t, err)
+	}
+	assert.True(t, time.Since(start) >= 40*time.Millisecond)
+	assert.Equal(t, int32(5), inner.calls)
+}
+
+// tests that a limit of 0 or less doesn't limit the requests
+func TestRateLimitedUnlimited(t *testing.T) {
+	for _, perSecond := range []int{0, -1} {
+		inner := &countingProvider{}
+		l := NewRateLimited(inner, perSecond)
+
+		start := time.Now()
+		for i := 0; i < 5; i++ {
+			_, err := l.GetTaxCandidates(context.Background(), testAddress("US", "CA", "", "90401", ""))
+			assert.NoError(t, err)
+		}
+		assert.True(t, time.Since(start) < 100*time.Millisecond)
+		assert.Equal(t, int32(5), inner.calls)
+	}
+}
+
+// tests that a lookup waiting for its turn stops when the context is done
+func TestRateLimitedContext(t *testing.T) {
+	inner := &countingProvider{}
+	l := NewRateLimited(inner, 1)
+	_, err := l.GetTaxes(context.Background(), testAddress("US", "CA", "", "90401", ""))
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	start := time.Now()
+	_, err = l.GetTaxes(ctx, testAddress("US", "CA", "", "90401", ""))
+	assert.Equal(t, context.Canceled, err)
+	assert.True(t, time.Since(start) < 500*time.Millisecond)
+
+	// turns after the deadline are rejected without waiting
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = l.GetTaxes(ctx, testAddress("US", "CA", "", "90401", ""))
+	assert.Equal(t, 429, err.(*apperrors.Error).StatusCode())
+	assert.True(t, err.(*apperrors.Error).RetryAfter() > 0)
+	assert.Equal(t, int32(1), inner.calls)
+}
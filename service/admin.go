@@ -0,0 +1,56 @@
+package service
+
+import (
+	"context"
+	"github.com/renanrt/lab-go-api/model"
+	"github.com/renanrt/lab-go-api/provider"
+)
+
+// Migration reports the retailers and taxes changed by MigrateRateChanges
+type Migration struct {
+	Retailers []string `json:"retailers"`
+	Taxes     int      `json:"taxes"`
+}
+
+// PurgeCache removes the cached lookups of all providers, returning how many were removed
+func (service *Service) PurgeCache() int {
+	return service.providers().Purge()
+}
+
+// ProviderStatus returns the status of the providers available to the service
+func (service *Service) ProviderStatus() []*provider.Status {
+	return service.providers().Status()
+}
+
+// MigrateRateChanges schedules the new rates in the saved taxes of every affected retailer.
+// Retailers that already have the new rate are skipped, so a migration can be run again safely.
+func (service *Service) MigrateRateChanges(ctx context.Context, changes []*model.RateChange) (*Migration, error) {
+	migration := &Migration{Retailers: []string{}}
+	defer service.resetAffectedRetailers()
+
+	retailerIDs, err := service.store().RetailerIDs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, retailerID := range retailerIDs {
+		migrated := false
+		for _, rc := range changes {
+			// read the taxes again, a previous change may have scheduled new ones
+			taxes, err := service.store().GetTaxes(ctx, retailerID)
+			if err != nil {
+				return nil, err
+			}
+			for _, tax := range rc.Migrate(taxes) {
+				if err := service.store().SaveTax(ctx, tax); err != nil {
+					return nil, err
+				}
+				migration.Taxes++
+				migrated = true
+			}
+		}
+		if migrated {
+			migration.Retailers = append(migration.Retailers, retailerID)
+		}
+	}
+	return migration, nil
+}
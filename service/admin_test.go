@@ -0,0 +1,36 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/renanrt/lab-go-api/model"
+	"github.com/renanrt/lab-go-api/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMigrateRateChanges(t *testing.T) {
+	ctx := context.Background()
+	s := store.NewMemoryStore()
+	s.SaveTax(ctx, &model.Tax{RetailerID: "retailer-1", Name: "Tennessee", Rate: 0.07, Type: model.TaxTypeState})
+	s.SaveTax(ctx, &model.Tax{RetailerID: "retailer-2", Name: "California", Rate: 0.0725, Type: model.TaxTypeState})
+
+	service := &Service{Store: s}
+	changes := service.calendar().Between(time.Date(2027, time.January, 1, 0, 0, 0, 0, time.UTC), time.Time{}, "US", "TN")
+
+	migration, err := service.MigrateRateChanges(ctx, changes)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"retailer-1"}, migration.Retailers)
+	assert.Equal(t, 2, migration.Taxes)
+
+	taxes, _ := s.GetTaxes(ctx, "retailer-1")
+	active := model.ActiveTaxes(taxes, time.Date(2027, time.February, 1, 0, 0, 0, 0, time.UTC))
+	assert.Len(t, active, 1)
+	assert.Equal(t, changes[0].NewRate, active[0].Rate)
+
+	// running it again changes nothing
+	migration, err = service.MigrateRateChanges(ctx, changes)
+	assert.NoError(t, err)
+	assert.Empty(t, migration.Retailers)
+}
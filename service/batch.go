@@ -0,0 +1,129 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/renanrt/lab-go-api/address"
+	"github.com/renanrt/lab-go-api/apperrors"
+	"github.com/renanrt/lab-go-api/model"
+)
+
+const (
+	// MaxBatchSize is the max number of addresses in a batch search
+	MaxBatchSize = 1000
+	// DefaultParallelism is the number of concurrent lookups of a batch when MaxParallelism is not set
+	DefaultParallelism = 8
+)
+
+// BatchResult is the result of the search for one address of a batch
+type BatchResult struct {
+	// Index is the position of the address in the batch
+	Index      int
+	Address    address.Address
+	Candidates []*model.TaxGroup
+	Err        error
+}
+
+// FindTaxGroupsBatch finds the tax groups of each address. The results have the same order
+// of the addresses, and each one has either the candidates or the error of that address.
+func (service *Service) FindTaxGroupsBatch(ctx context.Context, asOf time.Time, providerName, retailerId string, addresses []address.Address) ([]*BatchResult, error) {
+	results := make([]*BatchResult, len(addresses))
+	err := service.FindTaxGroupsBatchFunc(ctx, asOf, providerName, retailerId, addresses, func(result *BatchResult) {
+		results[result.Index] = result
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// FindTaxGroupsBatchFunc finds the tax groups of each address, running up to MaxParallelism
+// lookups concurrently, and calls fn with each result as soon as it's available. fn is never
+// called concurrently. Lookups go through the same providers of single searches, so their
+// cache and rate limits are respected, and repeated addresses are only looked up once.
+// When the context is done, the pending addresses are skipped and its error is returned.
+func (service *Service) FindTaxGroupsBatchFunc(ctx context.Context, asOf time.Time, providerName, retailerId string, addresses []address.Address, fn func(*BatchResult)) error {
+	if len(addresses) > MaxBatchSize {
+		return apperrors.Validation(fmt.Sprintf("a batch can have at most %d addresses", MaxBatchSize), nil)
+	}
+
+	fnMu := sync.Mutex{}
+	send := func(result *BatchResult) {
+		fnMu.Lock()
+		defer fnMu.Unlock()
+		fn(result)
+	}
+
+	normalized := make([]address.Address, len(addresses))
+	byAddress := map[string][]int{}
+	keys := []string{}
+	for i, a := range addresses {
+		normalized[i] = a.Normalize()
+		if err := normalized[i].Validate(); err != nil {
+			send(&BatchResult{Index: i, Address: normalized[i], Err: err})
+			continue
+		}
+		key := batchKey(normalized[i])
+		if _, ok := byAddress[key]; !ok {
+			keys = append(keys, key)
+		}
+		byAddress[key] = append(byAddress[key], i)
+	}
+
+	// The taxes of the retailer are indexed once for the whole batch
+	idx, err := service.retailerTaxIndex(ctx, asOf, retailerId)
+	if err != nil {
+		return err
+	}
+
+	parallelism := service.MaxParallelism
+	if parallelism <= 0 {
+		parallelism = DefaultParallelism
+	}
+	semaphore := make(chan struct{}, parallelism)
+	wg := sync.WaitGroup{}
+
+	for _, key := range keys {
+		// addresses that didn't start when the context is done are not looked up
+		select {
+		case semaphore <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+		go func(indexes []int) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+
+			a := normalized[indexes[0]]
+			candidates, err := service.FindTaxGroups(ctx, asOf, providerName, a)
+			if err == nil {
+				reconcile(idx, candidates)
+			}
+			for n, i := range indexes {
+				result := &BatchResult{Index: i, Address: normalized[i], Err: err}
+				if err == nil {
+					result.Candidates = candidates
+					if n > 0 {
+						result.Candidates = model.CopyTaxGroups(candidates)
+					}
+				}
+				send(result)
+			}
+		}(byAddress[key])
+	}
+	wg.Wait()
+
+	return ctx.Err()
+}
+
+func batchKey(a address.Address) string {
+	return strings.Join([]string{a.Country, a.State, a.City, a.Zipcode, a.Street}, "|")
+}
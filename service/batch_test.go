@@ -0,0 +1,122 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/renanrt/lab-go-api/address"
+	"github.com/renanrt/lab-go-api/apperrors"
+	"github.com/renanrt/lab-go-api/model"
+	"github.com/renanrt/lab-go-api/provider"
+	"github.com/renanrt/lab-go-api/store"
+	"github.com/stretchr/testify/assert"
+)
+
+// concurrencyProvider records the max number of concurrent lookups
+type concurrencyProvider struct {
+	mu      sync.Mutex
+	running int
+	max     int
+	calls   int
+}
+
+func (p *concurrencyProvider) Name() string {
+	return "concurrency"
+}
+
+func (p *concurrencyProvider) GetTaxes(ctx context.Context, addr address.Address) (*model.TaxGroup, error) {
+	p.mu.Lock()
+	p.running++
+	p.calls++
+	if p.running > p.max {
+		p.max = p.running
+	}
+	p.mu.Unlock()
+
+	time.Sleep(5 * time.Millisecond)
+
+	p.mu.Lock()
+	p.running--
+	p.mu.Unlock()
+
+	taxGroup := &model.TaxGroup{}
+	taxGroup.AddTaxRate(model.NewTaxRate(model.TaxTypeState, addr.State, 0.05))
+	return taxGroup, nil
+}
+
+func TestFindTaxGroupsBatch(t *testing.T) {
+	ctx := context.Background()
+	service := &Service{}
+
+	results, err := service.FindTaxGroupsBatch(ctx, time.Now(), "", "retailer-id", []address.Address{
+		{Country: "US", State: "CA", Zipcode: "90401"},
+		{Country: "US", State: "ZZ", Zipcode: "90401"},
+		{Country: "US", Zipcode: "37027"},
+		{Country: "CA", State: "Ontario", Zipcode: "M5V2T6"},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, results, 4)
+
+	assert.NoError(t, results[0].Err)
+	assert.Len(t, results[0].Candidates, 1)
+
+	validationErr, ok := results[1].Err.(*apperrors.Error)
+	assert.True(t, ok)
+	assert.Equal(t, apperrors.CodeInvalidAddress, validationErr.Code)
+
+	assert.NoError(t, results[2].Err)
+	assert.Len(t, results[2].Candidates, 2)
+
+	assert.NoError(t, results[3].Err)
+	assert.Equal(t, "ON", results[3].Address.State)
+	assert.Len(t, results[3].Candidates[0].GetTaxByType(model.TaxTypeHST), 1)
+}
+
+// tests that the lookups run concurrently, up to MaxParallelism, and repeated addresses are looked up once
+func TestFindTaxGroupsBatchParallelism(t *testing.T) {
+	ctx := context.Background()
+	p := &concurrencyProvider{}
+	service := &Service{Providers: provider.NewRegistry(p), MaxParallelism: 3}
+
+	addresses := []address.Address{}
+	for _, state := range []string{"AL", "AK", "AZ", "AR", "CA", "CO", "CT", "DE", "FL", "GA"} {
+		addresses = append(addresses, address.Address{Country: "US", State: state, Zipcode: "00000"})
+	}
+	addresses = append(addresses, address.Address{Country: "US", State: "CA", Zipcode: "00000"})
+
+	results, err := service.FindTaxGroupsBatch(ctx, time.Now(), "", "retailer-id", addresses)
+	assert.NoError(t, err)
+	assert.Len(t, results, 11)
+	assert.Equal(t, 10, p.calls)
+	assert.True(t, p.max > 1)
+	assert.True(t, p.max <= 3)
+	assert.Equal(t, "CA", results[10].Candidates[0].Rates[0].Name)
+	assert.False(t, results[4].Candidates[0] == results[10].Candidates[0])
+
+	_, err = service.FindTaxGroupsBatch(ctx, time.Now(), "", "retailer-id", make([]address.Address, MaxBatchSize+1))
+	assert.Error(t, err)
+}
+
+// tests that the results of a batch are reconciled with the taxes of the retailer, and the
+// lookups themselves are not
+func TestFindTaxGroupsBatchReconcile(t *testing.T) {
+	ctx := context.Background()
+	service := &Service{Store: store.NewMemoryStore()}
+	assert.NoError(t, service.SaveRetailerTax(ctx, "retailer-1", &model.Tax{Name: "California", Rate: 0.0725, Type: model.TaxTypeState, VendTaxID: "vend-ca"}))
+
+	addresses := []address.Address{
+		{Country: "US", State: "CA", Zipcode: "90401"},
+		{Country: "US", State: "CA", Zipcode: "90046"},
+	}
+	results, err := service.FindTaxGroupsBatch(ctx, time.Now(), "", "retailer-1", addresses)
+	assert.NoError(t, err)
+	for _, result := range results {
+		assert.Equal(t, "vend-ca", result.Candidates[0].GetTaxByType(model.TaxTypeState)[0].VendTaxID)
+	}
+
+	candidates, err := service.FindTaxGroups(ctx, time.Now(), "", addresses[0])
+	assert.NoError(t, err)
+	assert.Empty(t, candidates[0].GetTaxByType(model.TaxTypeState)[0].VendTaxID)
+}
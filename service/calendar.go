@@ -0,0 +1,113 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/renanrt/lab-go-api/model"
+)
+
+// RateCalendar keeps the announced future rate changes per jurisdiction
+type RateCalendar struct {
+	changes []*model.RateChange
+}
+
+// NewRateCalendar creates a calendar sorted by effective date
+func NewRateCalendar(changes ...*model.RateChange) *RateCalendar {
+	c := &RateCalendar{}
+	c.Add(changes...)
+	return c
+}
+
+// LoadRateCalendar creates a calendar from a JSON data file with a list of rate changes
+func LoadRateCalendar(path string) (*RateCalendar, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	changes := []*model.RateChange{}
+	if err := json.NewDecoder(f).Decode(&changes); err != nil {
+		return nil, err
+	}
+	return NewRateCalendar(changes...), nil
+}
+
+// Add adds rate changes to the calendar
+func (c *RateCalendar) Add(changes ...*model.RateChange) {
+	c.changes = append(c.changes, changes...)
+	sort.SliceStable(c.changes, func(i, j int) bool {
+		return c.changes[i].EffectiveDate.Before(c.changes[j].EffectiveDate)
+	})
+}
+
+// Between returns copies of the changes effective in the period, country and state
+func (c *RateCalendar) Between(from, to time.Time, country, state string) []*model.RateChange {
+	changes := []*model.RateChange{}
+	for _, rc := range c.changes {
+		if rc.IsIn(from, to, country, state) {
+			change := *rc
+			changes = append(changes, &change)
+		}
+	}
+	return changes
+}
+
+// AllRetailers makes GetRateChanges flag every affected retailer, for the staff
+const AllRetailers = "*"
+
+// affectedCache keeps the retailers affected by each change of the calendar, so the rate changes
+// don't read the taxes of every retailer on each request. It's reset when taxes are saved.
+type affectedCache struct {
+	mu       sync.Mutex
+	byChange map[string][]string
+}
+
+// affectedRetailers returns the retailers affected by each change of the calendar, by rateChangeKey
+func (service *Service) affectedRetailers(ctx context.Context) (map[string][]string, error) {
+	service.affected.mu.Lock()
+	defer service.affected.mu.Unlock()
+	if service.affected.byChange != nil {
+		return service.affected.byChange, nil
+	}
+
+	changes := service.calendar().Between(time.Time{}, time.Time{}, "", "")
+	retailerIDs, err := service.store().RetailerIDs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	byChange := map[string][]string{}
+	for _, retailerID := range retailerIDs {
+		taxes, err := service.store().GetTaxes(ctx, retailerID)
+		if err != nil {
+			return nil, err
+		}
+		for _, rc := range changes {
+			if rc.Affects(taxes) {
+				key := rateChangeKey(rc)
+				byChange[key] = append(byChange[key], retailerID)
+			}
+		}
+	}
+	service.affected.byChange = byChange
+	return byChange, nil
+}
+
+// resetAffectedRetailers clears the cache after the taxes of a retailer change
+func (service *Service) resetAffectedRetailers() {
+	service.affected.mu.Lock()
+	defer service.affected.mu.Unlock()
+	service.affected.byChange = nil
+}
+
+// rateChangeKey identifies a change, as the calendar returns copies of them
+func rateChangeKey(rc *model.RateChange) string {
+	return fmt.Sprintf("%s|%s|%s|%s|%v|%v|%s|%v", rc.Country, rc.State, rc.Type, rc.Name, rc.OldRate, rc.NewRate,
+		rc.EffectiveDate.UTC().Format(time.RFC3339Nano), rc.Jurisdiction)
+}
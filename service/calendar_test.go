@@ -0,0 +1,58 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/renanrt/lab-go-api/model"
+	"github.com/renanrt/lab-go-api/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadRateCalendar(t *testing.T) {
+	calendar, err := LoadRateCalendar("../etc/rate-changes.json")
+	assert.NoError(t, err)
+
+	changes := calendar.Between(time.Date(2027, time.January, 1, 0, 0, 0, 0, time.UTC), time.Time{}, "", "")
+	assert.Len(t, changes, 2)
+	assert.Equal(t, "BC", changes[0].State)
+	assert.Equal(t, "WA", changes[1].State)
+
+	_, err = LoadRateCalendar("missing.json")
+	assert.Error(t, err)
+}
+
+// tests that retailers with the old rate saved are flagged before the change
+func TestGetRateChangesAffectedRetailers(t *testing.T) {
+	ctx := context.Background()
+	s := store.NewMemoryStore()
+	s.SaveTax(ctx, &model.Tax{RetailerID: "retailer-1", Name: "Tennessee", Rate: 0.07, Type: model.TaxTypeState})
+	s.SaveTax(ctx, &model.Tax{RetailerID: "retailer-2", Name: "California", Rate: 0.0725, Type: model.TaxTypeState})
+
+	service := &Service{Store: s}
+	service.now = func() time.Time { return time.Date(2026, time.October, 1, 0, 0, 0, 0, time.UTC) }
+
+	changes, err := service.GetRateChanges(ctx, service.clock(), time.Time{}, "US", "TN", AllRetailers)
+	assert.NoError(t, err)
+	assert.Len(t, changes, 1)
+	assert.Equal(t, []string{"retailer-1"}, changes[0].AffectedRetailers)
+
+	// retailers only see themselves
+	changes, _ = service.GetRateChanges(ctx, service.clock(), time.Time{}, "US", "TN", "retailer-1")
+	assert.Equal(t, []string{"retailer-1"}, changes[0].AffectedRetailers)
+	changes, _ = service.GetRateChanges(ctx, service.clock(), time.Time{}, "US", "TN", "retailer-2")
+	assert.Empty(t, changes[0].AffectedRetailers)
+
+	// saving taxes updates the cached retailers
+	assert.NoError(t, service.SaveRetailerTax(ctx, "retailer-3", &model.Tax{Name: "Tennessee", Rate: 0.07, Type: model.TaxTypeState}))
+	changes, _ = service.GetRateChanges(ctx, service.clock(), time.Time{}, "US", "TN", AllRetailers)
+	assert.Equal(t, []string{"retailer-1", "retailer-3"}, changes[0].AffectedRetailers)
+
+	// after the change date the retailers are not flagged anymore
+	service.now = func() time.Time { return time.Date(2027, time.February, 1, 0, 0, 0, 0, time.UTC) }
+	changes, err = service.GetRateChanges(ctx, time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC), time.Time{}, "US", "TN", AllRetailers)
+	assert.NoError(t, err)
+	assert.Len(t, changes, 1)
+	assert.Empty(t, changes[0].AffectedRetailers)
+}
@@ -0,0 +1,219 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/renanrt/lab-go-api/address"
+	"github.com/renanrt/lab-go-api/apperrors"
+	"github.com/renanrt/lab-go-api/logging"
+	"github.com/renanrt/lab-go-api/model"
+	"github.com/renanrt/lab-go-api/store"
+)
+
+const (
+	// MaxJobSize is the max number of addresses in a job
+	MaxJobSize = 100000
+	// jobChunkSize is the number of addresses looked up before saving the progress of a job
+	jobChunkSize = 100
+)
+
+// runningJobs are the jobs running in background in a service
+type runningJobs struct {
+	// mu serializes the updates of jobs, so a cancellation is not overwritten by the progress
+	mu sync.Mutex
+	// cancels stops the lookups of the running jobs, by job id
+	cancels map[string]context.CancelFunc
+}
+
+// SubmitJob saves a new job for the addresses and starts processing it in background.
+// The job keeps running after the request is done, until it's finished or cancelled.
+func (service *Service) SubmitJob(ctx context.Context, retailerID, providerName string, asOf time.Time, items []*model.JobItem) (*model.Job, error) {
+	if len(items) == 0 {
+		return nil, apperrors.Validation("a job needs at least one address", nil)
+	}
+	if len(items) > MaxJobSize {
+		return nil, apperrors.Validation(fmt.Sprintf("a job can have at most %d addresses", MaxJobSize), nil)
+	}
+
+	now := service.clock()
+	job := &model.Job{
+		RetailerID: retailerID,
+		Status:     model.JobStatusPending,
+		Provider:   providerName,
+		AsOf:       asOf,
+		Total:      len(items),
+		CreatedAt:  now,
+		UpdatedAt:  now,
+		Items:      items,
+	}
+	if err := service.store().SaveJob(ctx, job); err != nil {
+		return nil, err
+	}
+
+	service.startJob(job.ID)
+	return job, nil
+}
+
+// GetJob returns a job of the retailer, or store.ErrNotFound
+func (service *Service) GetJob(ctx context.Context, retailerID, id string) (*model.Job, error) {
+	job, err := service.store().GetJob(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if job.RetailerID != retailerID {
+		return nil, store.ErrNotFound
+	}
+	return job, nil
+}
+
+// CancelJob stops a job of the retailer, including its lookups in progress. Results already processed are kept
+func (service *Service) CancelJob(ctx context.Context, retailerID, id string) (*model.Job, error) {
+	service.jobs.mu.Lock()
+	defer service.jobs.mu.Unlock()
+
+	job, err := service.GetJob(ctx, retailerID, id)
+	if err != nil {
+		return nil, err
+	}
+	if job.IsFinished() {
+		return job, nil
+	}
+
+	job.Status = model.JobStatusCancelled
+	job.UpdatedAt = service.clock()
+	if err := service.store().SaveJob(ctx, job); err != nil {
+		return nil, err
+	}
+	if cancel, ok := service.jobs.cancels[id]; ok {
+		cancel()
+	}
+	return job, nil
+}
+
+// ResumeJobs restarts the processing of the jobs that were pending or running when the
+// process stopped. It should be called once at startup.
+func (service *Service) ResumeJobs(ctx context.Context) error {
+	jobs, err := service.store().UnfinishedJobs(ctx)
+	if err != nil {
+		return err
+	}
+	for _, job := range jobs {
+		service.startJob(job.ID)
+	}
+	if len(jobs) > 0 {
+		service.logger().Info("resumed the unfinished jobs", logging.Fields{"jobs": len(jobs)})
+	}
+	return nil
+}
+
+// startJob runs a job in background, with a context that is cancelled by CancelJob
+// and has a logger with the job id
+func (service *Service) startJob(id string) {
+	ctx, cancel := context.WithCancel(context.Background())
+	ctx = logging.NewContext(ctx, service.logger().With(logging.Fields{"job_id": id}))
+	service.jobs.mu.Lock()
+	if service.jobs.cancels == nil {
+		service.jobs.cancels = map[string]context.CancelFunc{}
+	}
+	service.jobs.cancels[id] = cancel
+	service.jobs.mu.Unlock()
+
+	go func() {
+		defer func() {
+			service.jobs.mu.Lock()
+			delete(service.jobs.cancels, id)
+			service.jobs.mu.Unlock()
+			cancel()
+		}()
+		service.runJob(ctx, id)
+	}()
+}
+
+// runJob looks up the addresses of a job in chunks, saving the progress after each one,
+// until all addresses are processed or the job is cancelled
+func (service *Service) runJob(ctx context.Context, id string) {
+	for {
+		service.jobs.mu.Lock()
+		job, err := service.store().GetJob(ctx, id)
+		service.jobs.mu.Unlock()
+		if err != nil || job.IsFinished() {
+			return
+		}
+
+		end := job.Processed + jobChunkSize
+		if end > job.Total {
+			end = job.Total
+		}
+		chunk := job.Items[job.Processed:end]
+		addresses := make([]address.Address, 0, len(chunk))
+		for _, item := range chunk {
+			addresses = append(addresses, address.Address{Country: item.Country, State: item.State, City: item.City, Zipcode: item.Zipcode, Street: item.Street})
+		}
+		results, batchErr := service.FindTaxGroupsBatch(ctx, job.AsOf, job.Provider, job.RetailerID, addresses)
+
+		if !service.saveJobProgress(ctx, id, chunk, results, batchErr) {
+			return
+		}
+	}
+}
+
+// saveJobProgress adds the results of a chunk to the job. It returns false when the job is finished
+func (service *Service) saveJobProgress(ctx context.Context, id string, chunk []*model.JobItem, results []*BatchResult, batchErr error) bool {
+	service.jobs.mu.Lock()
+	defer service.jobs.mu.Unlock()
+
+	job, err := service.store().GetJob(ctx, id)
+	if err != nil || job.IsFinished() {
+		return false
+	}
+
+	job.UpdatedAt = service.clock()
+	if batchErr != nil {
+		job.Status = model.JobStatusFailed
+		job.Error = batchErr.Error()
+	} else {
+		job.Status = model.JobStatusRunning
+		for i, result := range results {
+			jobResult := &model.JobResult{ID: chunk[i].ID, Status: http.StatusOK, Candidates: result.Candidates}
+			if result.Err != nil {
+				jobResult.Status = errorStatusCode(result.Err)
+				jobResult.Error = result.Err.Error()
+				job.Failed++
+			} else if len(result.Candidates) > 1 {
+				jobResult.Status = http.StatusMultipleChoices
+			}
+			job.Results = append(job.Results, jobResult)
+		}
+		job.Processed += len(chunk)
+		if job.Processed >= job.Total {
+			job.Status = model.JobStatusCompleted
+		}
+	}
+
+	if err := service.store().SaveJob(ctx, job); err != nil {
+		logging.FromContext(ctx).Error("saving the job progress failed", logging.Fields{"error": err})
+		return false
+	}
+
+	switch job.Status {
+	case model.JobStatusFailed:
+		logging.FromContext(ctx).Error("job failed", logging.Fields{"retailer": job.RetailerID, "error": job.Error})
+	case model.JobStatusCompleted:
+		logging.FromContext(ctx).Info("job completed", logging.Fields{"retailer": job.RetailerID, "total": job.Total, "failed": job.Failed})
+	}
+	return !job.IsFinished()
+}
+
+// errorStatusCode returns the http status code of errors that define one, or 500
+func errorStatusCode(err error) int {
+	if statusCodeErr, ok := err.(interface {
+		StatusCode() int
+	}); ok {
+		return statusCodeErr.StatusCode()
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,95 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/renanrt/lab-go-api/model"
+	"github.com/renanrt/lab-go-api/store"
+	"github.com/stretchr/testify/assert"
+)
+
+// waitForJob polls the job until it's finished
+func waitForJob(t *testing.T, service *Service, id string) *model.Job {
+	for i := 0; i < 200; i++ {
+		job, err := service.GetJob(context.Background(), "retailer-id", id)
+		assert.NoError(t, err)
+		if job.IsFinished() {
+			return job
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatal("job didn't finish")
+	return nil
+}
+
+func newJobItems(n int) []*model.JobItem {
+	items := []*model.JobItem{}
+	for i := 0; i < n; i++ {
+		items = append(items, &model.JobItem{ID: "outlet", Country: "US", State: "CA", Zipcode: "90401"})
+	}
+	items[n-1].Zipcode = ""
+	return items
+}
+
+func TestSubmitJob(t *testing.T) {
+	ctx := context.Background()
+	service := &Service{Store: store.NewMemoryStore()}
+
+	job, err := service.SubmitJob(ctx, "retailer-id", "", time.Now(), newJobItems(250))
+	assert.NoError(t, err)
+	assert.Equal(t, model.JobStatusPending, job.Status)
+
+	job = waitForJob(t, service, job.ID)
+	assert.Equal(t, model.JobStatusCompleted, job.Status)
+	assert.Equal(t, 250, job.Processed)
+	assert.Equal(t, 1, job.Failed)
+	assert.Len(t, job.Results, 250)
+	assert.Equal(t, 200, job.Results[0].Status)
+	assert.Equal(t, 400, job.Results[249].Status)
+
+	_, err = service.GetJob(ctx, "another-retailer", job.ID)
+	assert.Equal(t, store.ErrNotFound, err)
+
+	_, err = service.SubmitJob(ctx, "retailer-id", "", time.Now(), nil)
+	assert.Error(t, err)
+}
+
+func TestCancelJob(t *testing.T) {
+	ctx := context.Background()
+	s := store.NewMemoryStore()
+	service := &Service{Store: s}
+
+	job := &model.Job{RetailerID: "retailer-id", Status: model.JobStatusPending, Total: 250, Items: newJobItems(250)}
+	assert.NoError(t, s.SaveJob(ctx, job))
+
+	job, err := service.CancelJob(ctx, "retailer-id", job.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, model.JobStatusCancelled, job.Status)
+
+	// cancelled jobs are not processed anymore
+	service.runJob(ctx, job.ID)
+	job, err = service.GetJob(ctx, "retailer-id", job.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, job.Processed)
+}
+
+// tests that jobs that were running when the process stopped are resumed from their progress
+func TestResumeJobs(t *testing.T) {
+	ctx := context.Background()
+	s := store.NewMemoryStore()
+	service := &Service{Store: s}
+
+	job := &model.Job{RetailerID: "retailer-id", Status: model.JobStatusRunning, Total: 150, Processed: 100, Items: newJobItems(150)}
+	for i := 0; i < 100; i++ {
+		job.Results = append(job.Results, &model.JobResult{Status: 200})
+	}
+	assert.NoError(t, s.SaveJob(ctx, job))
+
+	assert.NoError(t, service.ResumeJobs(ctx))
+	job = waitForJob(t, service, job.ID)
+	assert.Equal(t, model.JobStatusCompleted, job.Status)
+	assert.Len(t, job.Results, 150)
+	assert.Equal(t, 1, job.Failed)
+}
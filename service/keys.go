@@ -0,0 +1,154 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/renanrt/lab-go-api/apperrors"
+	"github.com/renanrt/lab-go-api/auth"
+	"github.com/renanrt/lab-go-api/model"
+	"github.com/renanrt/lab-go-api/store"
+)
+
+const (
+	// apiKeyPrefix makes the keys easy to recognize, like in leaked credentials scans
+	apiKeyPrefix = "lgk_"
+	// apiKeyUsageInterval is how often the last use of a key is saved, so keys used on every
+	// request don't write to the store every time
+	apiKeyUsageInterval = time.Minute
+)
+
+var (
+	// ErrInvalidAPIKey is returned when an API key is created without name or with unknown scopes
+	ErrInvalidAPIKey = apperrors.Validation("name and valid scopes are mandatory", nil)
+	// ErrAPIKeyRevoked is returned when a revoked API key is rotated
+	ErrAPIKeyRevoked = apperrors.Conflict("the API key is revoked")
+	// ErrAPIKeyScopeDenied is returned when an API key is created with scopes the caller doesn't have
+	ErrAPIKeyScopeDenied = apperrors.Forbidden("API keys can't have scopes that you don't have")
+	// ErrAPIKeyImpersonated is returned when staff impersonating a retailer creates or rotates a key,
+	// so they never see the secrets of the retailer
+	ErrAPIKeyImpersonated = apperrors.Forbidden("API keys can't be created or rotated while impersonating a retailer")
+)
+
+// CreateAPIKey creates an API key for the retailer of the caller, with at most the scopes of the caller.
+// The key itself is only returned here and by RotateAPIKey, the store only keeps its hash.
+func (service *Service) CreateAPIKey(ctx context.Context, caller *auth.Principal, name string, scopes []model.Scope) (*model.APIKey, string, error) {
+	if caller.Impersonator != "" {
+		return nil, "", ErrAPIKeyImpersonated
+	}
+	if name == "" || len(scopes) == 0 {
+		return nil, "", ErrInvalidAPIKey
+	}
+	for _, scope := range scopes {
+		if !model.IsValidScope(scope) {
+			return nil, "", ErrInvalidAPIKey
+		}
+	}
+	for _, scope := range scopes {
+		if !caller.HasScope(scope) {
+			return nil, "", ErrAPIKeyScopeDenied
+		}
+	}
+
+	key := &model.APIKey{RetailerID: caller.RetailerID, Name: name, Scopes: scopes, CreatedAt: service.clock()}
+	secret := newAPIKeySecret(key)
+	if err := service.store().SaveAPIKey(ctx, key); err != nil {
+		return nil, "", err
+	}
+	return key, secret, nil
+}
+
+// ListAPIKeys returns the API keys of the retailer, including the revoked ones
+func (service *Service) ListAPIKeys(ctx context.Context, retailerID string) ([]*model.APIKey, error) {
+	return service.store().APIKeys(ctx, retailerID)
+}
+
+// RotateAPIKey replaces the key of the retailer of the caller, keeping its name and scopes.
+// The previous key stops working.
+func (service *Service) RotateAPIKey(ctx context.Context, caller *auth.Principal, id string) (*model.APIKey, string, error) {
+	if caller.Impersonator != "" {
+		return nil, "", ErrAPIKeyImpersonated
+	}
+	key, err := service.getAPIKey(ctx, caller.RetailerID, id)
+	if err != nil {
+		return nil, "", err
+	}
+	if key.IsRevoked() {
+		return nil, "", ErrAPIKeyRevoked
+	}
+
+	now := service.clock()
+	key.RotatedAt = &now
+	secret := newAPIKeySecret(key)
+	if err := service.store().SaveAPIKey(ctx, key); err != nil {
+		return nil, "", err
+	}
+	return key, secret, nil
+}
+
+// RevokeAPIKey revokes the key, it can't be used anymore
+func (service *Service) RevokeAPIKey(ctx context.Context, retailerID, id string) (*model.APIKey, error) {
+	key, err := service.getAPIKey(ctx, retailerID, id)
+	if err != nil {
+		return nil, err
+	}
+	if key.IsRevoked() {
+		return key, nil
+	}
+
+	now := service.clock()
+	key.RevokedAt = &now
+	if err := service.store().SaveAPIKey(ctx, key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// LookupAPIKey finds the valid key, recording when it was used.
+// It returns nil when the key is unknown or revoked.
+func (service *Service) LookupAPIKey(ctx context.Context, secret string) (*model.APIKey, error) {
+	key, err := service.store().GetAPIKeyByHash(ctx, auth.HashKey(secret))
+	if err == store.ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if key.IsRevoked() {
+		return nil, nil
+	}
+
+	// Only the last use is written, so a rotation or revocation since the key was read is kept
+	now := service.clock()
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= apiKeyUsageInterval {
+		err := service.store().TouchAPIKey(ctx, key.ID, key.Hash, now)
+		if err == store.ErrNotFound {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		key.LastUsedAt = &now
+	}
+	return key, nil
+}
+
+// getAPIKey returns a key of the retailer, keys of other retailers are not found
+func (service *Service) getAPIKey(ctx context.Context, retailerID, id string) (*model.APIKey, error) {
+	key, err := service.store().GetAPIKey(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if key.RetailerID != retailerID {
+		return nil, store.ErrNotFound
+	}
+	return key, nil
+}
+
+// newAPIKeySecret generates a new key, and sets its hash and prefix
+func newAPIKeySecret(key *model.APIKey) string {
+	secret := apiKeyPrefix + store.NewID()
+	key.Hash = auth.HashKey(secret)
+	key.Prefix = secret[:len(apiKeyPrefix)+6]
+	return secret
+}
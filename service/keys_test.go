@@ -0,0 +1,156 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/renanrt/lab-go-api/auth"
+	"github.com/renanrt/lab-go-api/model"
+	"github.com/renanrt/lab-go-api/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPIKeys(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2018, time.July, 1, 0, 0, 0, 0, time.UTC)
+	service := &Service{Store: store.NewMemoryStore()}
+	service.now = func() time.Time { return now }
+
+	key, secret, err := service.CreateAPIKey(ctx, &auth.Principal{RetailerID: "retailer-1"}, "ecommerce", []model.Scope{model.ScopeSearch})
+	assert.NoError(t, err)
+	assert.Equal(t, "retailer-1", key.RetailerID)
+	assert.NotEmpty(t, key.ID)
+	assert.NotContains(t, key.Hash, secret)
+	assert.Contains(t, secret, key.Prefix)
+
+	found, err := service.LookupAPIKey(ctx, secret)
+	assert.NoError(t, err)
+	assert.Equal(t, key.ID, found.ID)
+	assert.Equal(t, now, *found.LastUsedAt)
+
+	// other retailers can't see or change the key
+	_, _, err = service.RotateAPIKey(ctx, &auth.Principal{RetailerID: "retailer-2"}, key.ID)
+	assert.Equal(t, store.ErrNotFound, err)
+	keys, _ := service.ListAPIKeys(ctx, "retailer-2")
+	assert.Empty(t, keys)
+
+	rotated, newSecret, err := service.RotateAPIKey(ctx, &auth.Principal{RetailerID: "retailer-1"}, key.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, key.ID, rotated.ID)
+	assert.NotEqual(t, secret, newSecret)
+	found, _ = service.LookupAPIKey(ctx, secret)
+	assert.Nil(t, found)
+	found, _ = service.LookupAPIKey(ctx, newSecret)
+	assert.NotNil(t, found)
+
+	_, err = service.RevokeAPIKey(ctx, "retailer-1", key.ID)
+	assert.NoError(t, err)
+	found, _ = service.LookupAPIKey(ctx, newSecret)
+	assert.Nil(t, found)
+	_, _, err = service.RotateAPIKey(ctx, &auth.Principal{RetailerID: "retailer-1"}, key.ID)
+	assert.Equal(t, ErrAPIKeyRevoked, err)
+
+	keys, _ = service.ListAPIKeys(ctx, "retailer-1")
+	assert.Len(t, keys, 1)
+	assert.True(t, keys[0].IsRevoked())
+}
+
+func TestCreateAPIKeyInvalid(t *testing.T) {
+	ctx := context.Background()
+	service := &Service{Store: store.NewMemoryStore()}
+	caller := &auth.Principal{RetailerID: "retailer-1"}
+
+	_, _, err := service.CreateAPIKey(ctx, caller, "", []model.Scope{model.ScopeSearch})
+	assert.Equal(t, ErrInvalidAPIKey, err)
+	_, _, err = service.CreateAPIKey(ctx, caller, "ecommerce", nil)
+	assert.Equal(t, ErrInvalidAPIKey, err)
+	_, _, err = service.CreateAPIKey(ctx, caller, "ecommerce", []model.Scope{"admin"})
+	assert.Equal(t, ErrInvalidAPIKey, err)
+}
+
+// tests that keys can't be created with more scopes than the caller has
+func TestCreateAPIKeyScopes(t *testing.T) {
+	ctx := context.Background()
+	service := &Service{Store: store.NewMemoryStore()}
+	caller := &auth.Principal{RetailerID: "retailer-1", Scopes: []model.Scope{model.ScopeKeysManage, model.ScopeSearch}}
+
+	_, _, err := service.CreateAPIKey(ctx, caller, "ecommerce", []model.Scope{model.ScopeSearch, model.ScopeTaxesWrite})
+	assert.Equal(t, ErrAPIKeyScopeDenied, err)
+
+	_, _, err = service.CreateAPIKey(ctx, caller, "ecommerce", []model.Scope{model.ScopeSearch})
+	assert.NoError(t, err)
+}
+
+// tests that staff impersonating a retailer can't see the secrets of its keys
+func TestAPIKeysImpersonated(t *testing.T) {
+	ctx := context.Background()
+	service := &Service{Store: store.NewMemoryStore()}
+	key, _, err := service.CreateAPIKey(ctx, &auth.Principal{RetailerID: "retailer-1"}, "ecommerce", []model.Scope{model.ScopeSearch})
+	assert.NoError(t, err)
+
+	admin, _ := (&auth.Principal{Subject: "admin-1", Role: auth.RoleAdmin}).Impersonate("retailer-1")
+	_, _, err = service.CreateAPIKey(ctx, admin, "ecommerce", []model.Scope{model.ScopeSearch})
+	assert.Equal(t, ErrAPIKeyImpersonated, err)
+	_, _, err = service.RotateAPIKey(ctx, admin, key.ID)
+	assert.Equal(t, ErrAPIKeyImpersonated, err)
+
+	keys, _ := service.ListAPIKeys(ctx, "retailer-1")
+	assert.Len(t, keys, 1)
+	_, err = service.RevokeAPIKey(ctx, admin.RetailerID, key.ID)
+	assert.NoError(t, err)
+}
+
+// lookupStore runs afterLookup once after a key is read by its hash, like a request changing
+// the key while another one is authenticated with it
+type lookupStore struct {
+	store.Store
+	afterLookup func()
+}
+
+func (s *lookupStore) GetAPIKeyByHash(ctx context.Context, hash string) (*model.APIKey, error) {
+	key, err := s.Store.GetAPIKeyByHash(ctx, hash)
+	if s.afterLookup != nil {
+		afterLookup := s.afterLookup
+		s.afterLookup = nil
+		afterLookup()
+	}
+	return key, err
+}
+
+// tests that recording the use of a key doesn't undo a rotation or revocation made since it was read
+func TestLookupAPIKeyConcurrentChanges(t *testing.T) {
+	ctx := context.Background()
+	s := &lookupStore{Store: store.NewMemoryStore()}
+	service := &Service{Store: s}
+	caller := &auth.Principal{RetailerID: "retailer-1"}
+
+	key, secret, err := service.CreateAPIKey(ctx, caller, "ecommerce", []model.Scope{model.ScopeSearch})
+	assert.NoError(t, err)
+
+	var newSecret string
+	s.afterLookup = func() {
+		_, newSecret, err = service.RotateAPIKey(ctx, caller, key.ID)
+		assert.NoError(t, err)
+	}
+	found, err := service.LookupAPIKey(ctx, secret)
+	assert.NoError(t, err)
+	assert.Nil(t, found)
+	found, _ = service.LookupAPIKey(ctx, secret)
+	assert.Nil(t, found)
+	found, _ = service.LookupAPIKey(ctx, newSecret)
+	assert.NotNil(t, found)
+
+	key, secret, err = service.CreateAPIKey(ctx, caller, "pos", []model.Scope{model.ScopeSearch})
+	assert.NoError(t, err)
+	s.afterLookup = func() {
+		_, err = service.RevokeAPIKey(ctx, "retailer-1", key.ID)
+		assert.NoError(t, err)
+	}
+	found, err = service.LookupAPIKey(ctx, secret)
+	assert.NoError(t, err)
+	assert.Nil(t, found)
+	saved, _ := s.GetAPIKey(ctx, key.ID)
+	assert.True(t, saved.IsRevoked())
+	assert.Nil(t, saved.LastUsedAt)
+}
@@ -1,14 +1,341 @@
 package service
 
-import "github.com/renanrt/lab-go-api/model"
+import (
+	"context"
+	"fmt"
+	"time"
 
-// TaxService represents the tax recommendation service
-type TaxService struct {
+	"github.com/renanrt/lab-go-api/address"
+	"github.com/renanrt/lab-go-api/apperrors"
+	"github.com/renanrt/lab-go-api/auth"
+	"github.com/renanrt/lab-go-api/config"
+	"github.com/renanrt/lab-go-api/geo"
+	"github.com/renanrt/lab-go-api/logging"
+	"github.com/renanrt/lab-go-api/model"
+	"github.com/renanrt/lab-go-api/provider"
+	"github.com/renanrt/lab-go-api/store"
+)
+
+// TaxService is the tax recommendation service used by the api
+type TaxService interface {
+	TaxLookup
+	TaxReconciler
+	JobRunner
+	KeyManager
+	Administration
+}
+
+// TaxLookup finds the taxes for addresses and coordinates
+type TaxLookup interface {
+	FindTaxGroups(ctx context.Context, asOf time.Time, providerName string, addr address.Address) ([]*model.TaxGroup, error)
+	FindTaxGroupsForPoint(ctx context.Context, asOf time.Time, providerName, country string, point geo.Point) ([]*model.TaxGroup, error)
+	FindTaxGroupsBatch(ctx context.Context, asOf time.Time, providerName, retailerId string, addresses []address.Address) ([]*BatchResult, error)
+	FindTaxGroupsBatchFunc(ctx context.Context, asOf time.Time, providerName, retailerId string, addresses []address.Address, fn func(*BatchResult)) error
+	GetRateChanges(ctx context.Context, from, to time.Time, country, state, retailerID string) ([]*model.RateChange, error)
+}
+
+// TaxReconciler links the recommended taxes with the taxes saved by the retailers, and saves the accepted ones
+type TaxReconciler interface {
+	ReconcileTaxes(ctx context.Context, asOf time.Time, retailerID string, taxGroups ...*model.TaxGroup) error
+	AcceptTaxes(ctx context.Context, asOf time.Time, retailerID string, taxGroup *model.TaxGroup) ([]*model.Tax, error)
+	GetRetailerTaxes(ctx context.Context, asOf time.Time, retailerID string) ([]*model.Tax, error)
+	SaveRetailerTax(ctx context.Context, retailerID string, tax *model.Tax) error
+}
+
+// JobRunner runs the bulk lookup jobs
+type JobRunner interface {
+	SubmitJob(ctx context.Context, retailerID, providerName string, asOf time.Time, items []*model.JobItem) (*model.Job, error)
+	GetJob(ctx context.Context, retailerID, id string) (*model.Job, error)
+	CancelJob(ctx context.Context, retailerID, id string) (*model.Job, error)
+}
+
+// KeyManager manages the API keys of the retailers
+type KeyManager interface {
+	CreateAPIKey(ctx context.Context, caller *auth.Principal, name string, scopes []model.Scope) (*model.APIKey, string, error)
+	ListAPIKeys(ctx context.Context, retailerID string) ([]*model.APIKey, error)
+	RotateAPIKey(ctx context.Context, caller *auth.Principal, id string) (*model.APIKey, string, error)
+	RevokeAPIKey(ctx context.Context, retailerID, id string) (*model.APIKey, error)
+	LookupAPIKey(ctx context.Context, secret string) (*model.APIKey, error)
+}
+
+// Administration has the operations used by the admins
+type Administration interface {
+	PurgeCache() int
+	ProviderStatus() []*provider.Status
+	MigrateRateChanges(ctx context.Context, changes []*model.RateChange) (*Migration, error)
+}
+
+var _ TaxService = (*Service)(nil)
+
+// Service implements TaxService with the providers and the store
+type Service struct {
+	// Providers available to the service. The default registry is used when it's nil
+	Providers *provider.Registry
+	// Store where the retailer taxes are saved. An empty memory store is used when it's nil
+	Store store.Store
+	// Calendar of announced rate changes. When it's nil, the changes known by the providers are used
+	Calendar *RateCalendar
+	// Geocoder resolves coordinates to addresses, for providers that don't support coordinates
+	Geocoder geo.Geocoder
+	// MaxParallelism is the max number of concurrent lookups of a batch search
+	MaxParallelism int
+	// Logger records the background jobs. Lookups use the logger of their context.
+	// The default logger is used when it's nil
+	Logger *logging.Logger
+
+	// affected caches the retailers affected by the rate changes
+	affected affectedCache
+
+	// jobs are the jobs running in background
+	jobs runningJobs
+
+	// now is replaced in tests
+	now func() time.Time
+}
+
+// New creates a service with the offline provider, and the store, rate changes, boundaries,
+// cache and limits of the configuration
+func New(cfg config.Service, logger *logging.Logger) (*Service, error) {
+	var p provider.Provider = provider.NewOffline()
+	if cfg.ProviderRateLimit > 0 {
+		p = provider.NewRateLimited(p, cfg.ProviderRateLimit)
+	}
+	// The cache wraps the rate limit, so the cached lookups don't wait for a turn
+	if cfg.CacheTTL > 0 {
+		p = provider.NewCached(p, cfg.CacheTTL, cfg.CacheSize)
+	}
+
+	service := &Service{
+		Providers:      provider.NewRegistry(p),
+		Store:          store.NewMemoryStore(),
+		MaxParallelism: cfg.BatchParallelism,
+		Logger:         logger,
+	}
+	if cfg.StoreFile != "" {
+		s, err := store.OpenFileStore(cfg.StoreFile)
+		if err != nil {
+			return nil, err
+		}
+		service.Store = s
+	}
+	if cfg.BoundaryFile != "" {
+		geocoder, err := geo.LoadBoundaryGeocoder(cfg.BoundaryFile)
+		if err != nil {
+			return nil, err
+		}
+		service.Geocoder = geocoder
+	}
+	if cfg.RateChangesFile != "" {
+		calendar, err := LoadRateCalendar(cfg.RateChangesFile)
+		if err != nil {
+			return nil, err
+		}
+		service.Calendar = calendar
+	}
+	return service, nil
+}
+
+// GetTaxesForAddress returns the taxes for an address that are effective now
+func (service *Service) GetTaxesForAddress(ctx context.Context, providerName, retailerId string, addr address.Address) (*model.TaxGroup, error) {
+	return service.GetTaxesForAddressAsOf(ctx, service.clock(), providerName, retailerId, addr)
+}
+
+// GetRateChanges returns the announced rate changes effective between from and to.
+// Changes that didn't happen yet are flagged with the retailer if its saved taxes still
+// have the old rate. With AllRetailers, they are flagged with every affected retailer.
+func (service *Service) GetRateChanges(ctx context.Context, from, to time.Time, country, state, retailerID string) ([]*model.RateChange, error) {
+	changes := service.calendar().Between(from, to, country, state)
+
+	affected, err := service.affectedRetailers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	now := service.clock()
+	for _, rc := range changes {
+		if !rc.EffectiveDate.After(now) {
+			continue
+		}
+		for _, id := range affected[rateChangeKey(rc)] {
+			if retailerID == AllRetailers || id == retailerID {
+				rc.AffectedRetailers = append(rc.AffectedRetailers, id)
+			}
+		}
+	}
+	return changes, nil
+}
+
+// GetTaxesForAddressAsOf returns the taxes for an address that are effective at the asOf date,
+// reconciled with the taxes of the retailer.
+// It returns an *AmbiguousAddressError when the address matches more than one jurisdiction.
+func (service *Service) GetTaxesForAddressAsOf(ctx context.Context, asOf time.Time, providerName, retailerId string, addr address.Address) (*model.TaxGroup, error) {
+	candidates, err := service.FindTaxGroups(ctx, asOf, providerName, addr)
+	if err != nil {
+		return nil, err
+	}
+	if err := service.ReconcileTaxes(ctx, asOf, retailerId, candidates...); err != nil {
+		return nil, err
+	}
+	if len(candidates) > 1 {
+		return nil, &AmbiguousAddressError{Candidates: candidates}
+	}
+	return candidates[0], nil
+}
+
+// FindTaxGroups returns a tax group for each jurisdiction that matches the address, effective
+// at the asOf date and sorted by confidence. Ambiguous zipcodes return more than one group.
+// The groups are not linked with the taxes of a retailer, see ReconcileTaxes.
+// The provider lookup stops when the context is done, like when the client disconnects.
+func (service *Service) FindTaxGroups(ctx context.Context, asOf time.Time, providerName string, addr address.Address) ([]*model.TaxGroup, error) {
+	if !model.IsSupportedCountry(addr.Country) {
+		return nil, &apperrors.Error{
+			Code:    apperrors.CodeUnsupportedCountry,
+			Message: fmt.Sprintf("country %q is not supported", addr.Country),
+			Fields:  map[string]string{"country": "is not supported"},
+		}
+	}
+
+	p, err := service.providers().Get(providerName)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	var candidates []*model.TaxGroup
+	if candidateProvider, ok := p.(provider.CandidateProvider); ok {
+		candidates, err = candidateProvider.GetTaxCandidates(ctx, addr)
+	} else {
+		var taxGroup *model.TaxGroup
+		taxGroup, err = p.GetTaxes(ctx, addr)
+		candidates = []*model.TaxGroup{taxGroup}
+	}
+	if err != nil {
+		return nil, providerError(p, err)
+	}
+	logging.FromContext(ctx).Debug("looked up the taxes", logging.Fields{
+		"provider":    p.Name(),
+		"candidates":  len(candidates),
+		"duration_ms": time.Since(start).Nanoseconds() / int64(time.Millisecond),
+	})
+
+	return activeTaxGroups(asOf, addr.Country, candidates)
+}
+
+// FindTaxGroupsForPoint returns a tax group for each jurisdiction that contains the point,
+// effective at the asOf date. Providers that support coordinates receive them directly,
+// otherwise the point is resolved to an address with the Geocoder.
+func (service *Service) FindTaxGroupsForPoint(ctx context.Context, asOf time.Time, providerName, country string, point geo.Point) ([]*model.TaxGroup, error) {
+	p, err := service.providers().Get(providerName)
+	if err != nil {
+		return nil, err
+	}
+
+	if coordinateProvider, ok := p.(provider.CoordinateProvider); ok {
+		candidates, err := coordinateProvider.GetTaxesForPoint(ctx, country, point)
+		if err != provider.ErrPointNotSupported {
+			if err != nil {
+				return nil, providerError(p, err)
+			}
+			return activeTaxGroups(asOf, country, candidates)
+		}
+	}
+
+	if service.Geocoder == nil {
+		return nil, apperrors.New(apperrors.CodeInvalidCoordinates, "searching by coordinates is not available")
+	}
+	addr, err := service.Geocoder.ReverseGeocode(ctx, point)
+	if err == geo.ErrNotFound {
+		return nil, apperrors.New(apperrors.CodeNoTaxesFound, "no taxes found for the coordinates")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return service.FindTaxGroups(ctx, asOf, providerName, addr)
 }
 
-func (service *TaxService) GetTaxesForAddress(provider, retailerId, country, state, city, zipcode string, street string) (*model.TaxGroup, error) {
-	taxGroup := &model.TaxGroup{}
-	taxGroup.TotalRate = 1.0
-	taxGroup.AddTaxRate(model.NewTaxRate(model.TaxTypeCity, "my tax city", 1.0))
-	return taxGroup, nil
+// activeTaxGroups returns the candidates with the rates effective at the asOf date,
+// and validates them for the country
+func activeTaxGroups(asOf time.Time, country string, candidates []*model.TaxGroup) ([]*model.TaxGroup, error) {
+	if len(candidates) == 0 {
+		return nil, apperrors.New(apperrors.CodeNoTaxesFound, "no taxes found for the address")
+	}
+
+	active := make([]*model.TaxGroup, 0, len(candidates))
+	for _, taxGroup := range candidates {
+		taxGroup = taxGroup.ActiveAt(asOf)
+		if err := taxGroup.Validate(country); err != nil {
+			return nil, err
+		}
+		active = append(active, taxGroup)
+	}
+	return active, nil
 }
+
+// providerError returns the errors of the provider that are not meant for the clients,
+// like the errors of its api, as upstream errors. Cancellations and timeouts are returned
+// as they are, the api answers them with 499 and 504.
+func providerError(p provider.Provider, err error) error {
+	if _, ok := err.(*apperrors.Error); ok || err == context.Canceled || err == context.DeadlineExceeded {
+		return err
+	}
+	return apperrors.Upstream(p.Name(), err)
+}
+
+// AmbiguousAddressError is returned when an address matches more than one jurisdiction,
+// so the retailer needs to choose one of the candidates
+type AmbiguousAddressError struct {
+	Candidates []*model.TaxGroup
+}
+
+func (e *AmbiguousAddressError) Error() string {
+	return fmt.Sprintf("the address matches %d jurisdictions", len(e.Candidates))
+}
+
+// StatusCode is 409, the address is valid but the retailer needs to choose a jurisdiction
+func (e *AmbiguousAddressError) StatusCode() int {
+	return apperrors.CodeAmbiguousAddress.Status()
+}
+
+// ErrorCode is the apperrors code of ambiguous addresses
+func (e *AmbiguousAddressError) ErrorCode() int {
+	return int(apperrors.CodeAmbiguousAddress)
+}
+
+func (service *Service) providers() *provider.Registry {
+	if service.Providers == nil {
+		return defaultProviders
+	}
+	return service.Providers
+}
+
+func (service *Service) store() store.Store {
+	if service.Store == nil {
+		return defaultStore
+	}
+	return service.Store
+}
+
+func (service *Service) calendar() *RateCalendar {
+	if service.Calendar == nil {
+		return NewRateCalendar(service.providers().RateChanges()...)
+	}
+	return service.Calendar
+}
+
+func (service *Service) logger() *logging.Logger {
+	if service.Logger == nil {
+		return logging.Default()
+	}
+	return service.Logger
+}
+
+func (service *Service) clock() time.Time {
+	if service.now == nil {
+		return time.Now()
+	}
+	return service.now()
+}
+
+var (
+	defaultProviders = provider.DefaultRegistry()
+	defaultStore     = store.NewMemoryStore()
+)
@@ -1,9 +1,18 @@
 package service
 
 import (
+	"context"
+	"errors"
+	"net/http"
 	"testing"
+	"time"
 
+	"github.com/renanrt/lab-go-api/address"
+	"github.com/renanrt/lab-go-api/apperrors"
+	"github.com/renanrt/lab-go-api/config"
+	"github.com/renanrt/lab-go-api/geo"
 	"github.com/renanrt/lab-go-api/model"
+	"github.com/renanrt/lab-go-api/provider"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -13,3 +22,175 @@ func TestMergeTaxesAllNew(t *testing.T) {
 	assert.NotNil(t, taxGroup)
 
 }
+
+func TestGetTaxesForAddressCountry(t *testing.T) {
+	ctx := context.Background()
+	service := &Service{}
+
+	taxGroup, err := service.GetTaxesForAddress(ctx, "", "retailer-id", testAddress("CA", "ON", "Toronto", "M5V 2T6", ""))
+	assert.NoError(t, err)
+	assert.Len(t, taxGroup.GetTaxByType(model.TaxTypeHST), 1)
+
+	taxGroup, err = service.GetTaxesForAddress(ctx, "", "retailer-id", testAddress("", "CA", "Santa Monica", "90401", ""))
+	assert.NoError(t, err)
+	assert.Len(t, taxGroup.GetTaxByType(model.TaxTypeState), 1)
+
+	_, err = service.GetTaxesForAddress(ctx, "", "retailer-id", testAddress("ZZ", "", "", "00000", ""))
+	assert.Error(t, err)
+
+	_, err = service.GetTaxesForAddress(ctx, "unknown", "retailer-id", testAddress("US", "CA", "", "90401", ""))
+	assert.Error(t, err)
+}
+
+func TestNew(t *testing.T) {
+	service, err := New(config.Service{RateChangesFile: "../etc/rate-changes.json", CacheTTL: time.Hour, ProviderRateLimit: 10}, nil)
+	assert.NoError(t, err)
+	assert.NotNil(t, service.Calendar)
+	status := service.ProviderStatus()
+	assert.Len(t, status, 1)
+	assert.True(t, status[0].Cached)
+
+	_, err = service.GetTaxesForAddress(context.Background(), "", "retailer-id", testAddress("US", "CA", "Santa Monica", "90401", ""))
+	assert.NoError(t, err)
+
+	_, err = New(config.Service{RateChangesFile: "missing.json"}, nil)
+	assert.Error(t, err)
+}
+
+// tests that a cancelled request stops the provider lookup
+func TestFindTaxGroupsCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := (&Service{}).FindTaxGroups(ctx, time.Now(), "", testAddress("US", "CA", "", "90401", ""))
+	assert.Equal(t, context.Canceled, err)
+}
+
+// scheduledProvider returns a rate that changes on 2018-07-01
+type scheduledProvider struct{}
+
+func (p *scheduledProvider) Name() string {
+	return "scheduled"
+}
+
+func (p *scheduledProvider) GetTaxes(ctx context.Context, addr address.Address) (*model.TaxGroup, error) {
+	change := time.Date(2018, time.July, 1, 0, 0, 0, 0, time.UTC)
+	current := model.NewTaxRate(model.TaxTypeState, "California", 0.0725)
+	current.ValidTo = &change
+	scheduled := model.NewTaxRate(model.TaxTypeState, "California", 0.075)
+	scheduled.ValidFrom = &change
+
+	taxGroup := &model.TaxGroup{}
+	taxGroup.AddTaxRate(current)
+	taxGroup.AddTaxRate(scheduled)
+	return taxGroup, nil
+}
+
+func TestGetTaxesForAddressAsOf(t *testing.T) {
+	ctx := context.Background()
+	service := &Service{Providers: provider.NewRegistry(&scheduledProvider{})}
+
+	taxGroup, err := service.GetTaxesForAddressAsOf(ctx, time.Date(2018, time.June, 30, 0, 0, 0, 0, time.UTC), "", "retailer-id", testAddress("US", "CA", "", "90401", ""))
+	assert.NoError(t, err)
+	assert.InDelta(t, 0.0725, taxGroup.TotalRate, 0.0000001)
+
+	taxGroup, err = service.GetTaxesForAddressAsOf(ctx, time.Date(2018, time.July, 1, 0, 0, 0, 0, time.UTC), "", "retailer-id", testAddress("US", "CA", "", "90401", ""))
+	assert.NoError(t, err)
+	assert.InDelta(t, 0.075, taxGroup.TotalRate, 0.0000001)
+}
+
+func TestFindTaxGroupsAmbiguous(t *testing.T) {
+	ctx := context.Background()
+	service := &Service{}
+
+	candidates, err := service.FindTaxGroups(ctx, time.Now(), "", testAddress("US", "TN", "", "37027", ""))
+	assert.NoError(t, err)
+	assert.Len(t, candidates, 2)
+	assert.True(t, candidates[0].Confidence > candidates[1].Confidence)
+
+	_, err = service.GetTaxesForAddress(ctx, "", "retailer-id", testAddress("US", "TN", "", "37027", ""))
+	ambiguousErr, ok := err.(*AmbiguousAddressError)
+	assert.True(t, ok)
+	assert.Len(t, ambiguousErr.Candidates, 2)
+	assert.Equal(t, http.StatusConflict, ambiguousErr.StatusCode())
+	assert.Equal(t, int(apperrors.CodeAmbiguousAddress), ambiguousErr.ErrorCode())
+
+	taxGroup, err := service.GetTaxesForAddress(ctx, "", "retailer-id", testAddress("US", "TN", "Brentwood", "37027", ""))
+	assert.NoError(t, err)
+	assert.Len(t, taxGroup.GetTaxByType(model.TaxTypeCounty), 1)
+}
+
+// pointProvider supports coordinates lookups
+type pointProvider struct {
+	point geo.Point
+}
+
+func (p *pointProvider) Name() string {
+	return "point"
+}
+
+func (p *pointProvider) GetTaxes(ctx context.Context, addr address.Address) (*model.TaxGroup, error) {
+	return nil, errors.New("addresses are not supported")
+}
+
+func (p *pointProvider) GetTaxesForPoint(ctx context.Context, country string, point geo.Point) ([]*model.TaxGroup, error) {
+	p.point = point
+	taxGroup := &model.TaxGroup{}
+	taxGroup.AddTaxRate(model.NewTaxRate(model.TaxTypeState, "California", 0.0725))
+	return []*model.TaxGroup{taxGroup}, nil
+}
+
+func TestFindTaxGroupsForPoint(t *testing.T) {
+	ctx := context.Background()
+	geocoder, err := geo.LoadBoundaryGeocoder("../geo/testdata/boundaries.json")
+	assert.NoError(t, err)
+	service := &Service{Geocoder: geocoder}
+
+	candidates, err := service.FindTaxGroupsForPoint(ctx, time.Now(), "", "US", geo.Point{Lat: 34.09, Lng: -118.36})
+	assert.NoError(t, err)
+	assert.Len(t, candidates, 1)
+	assert.Len(t, candidates[0].GetTaxByType(model.TaxTypeCity), 1)
+	assert.Equal(t, "West Hollywood", candidates[0].GetTaxByType(model.TaxTypeCity)[0].Name)
+
+	_, err = service.FindTaxGroupsForPoint(ctx, time.Now(), "", "US", geo.Point{Lat: 40.71, Lng: -74.00})
+	assert.Equal(t, apperrors.CodeNoTaxesFound, err.(*apperrors.Error).Code)
+
+	_, err = (&Service{}).FindTaxGroupsForPoint(ctx, time.Now(), "", "US", geo.Point{Lat: 34.09, Lng: -118.36})
+	assert.Equal(t, apperrors.CodeInvalidCoordinates, err.(*apperrors.Error).Code)
+}
+
+// tests that the service built from the configuration searches by coordinates through the cache and the rate limit
+func TestNewFindTaxGroupsForPoint(t *testing.T) {
+	ctx := context.Background()
+	service, err := New(config.Service{BoundaryFile: "../geo/testdata/boundaries.json", CacheTTL: time.Hour, ProviderRateLimit: 10}, nil)
+	assert.NoError(t, err)
+
+	candidates, err := service.FindTaxGroupsForPoint(ctx, time.Now(), "", "US", geo.Point{Lat: 34.09, Lng: -118.36})
+	assert.NoError(t, err)
+	assert.Equal(t, "West Hollywood", candidates[0].GetTaxByType(model.TaxTypeCity)[0].Name)
+
+	// the coordinates of providers that support them go through the wrappers
+	p := &pointProvider{}
+	service.Providers = provider.NewRegistry(provider.NewCached(provider.NewRateLimited(p, 10), time.Hour, 100))
+	point := geo.Point{Lat: 34.09, Lng: -118.36}
+	_, err = service.FindTaxGroupsForPoint(ctx, time.Now(), "", "US", point)
+	assert.NoError(t, err)
+	assert.Equal(t, point, p.point)
+}
+
+// tests that providers supporting coordinates receive them directly
+func TestFindTaxGroupsForPointProvider(t *testing.T) {
+	ctx := context.Background()
+	p := &pointProvider{}
+	service := &Service{Providers: provider.NewRegistry(p)}
+
+	point := geo.Point{Lat: 34.09, Lng: -118.36}
+	candidates, err := service.FindTaxGroupsForPoint(ctx, time.Now(), "", "US", point)
+	assert.NoError(t, err)
+	assert.Len(t, candidates, 1)
+	assert.Equal(t, point, p.point)
+}
+
+func testAddress(country, state, city, zipcode, street string) address.Address {
+	return address.Address{Country: country, State: state, City: city, Zipcode: zipcode, Street: street}
+}
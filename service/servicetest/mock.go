@@ -0,0 +1,172 @@
+// Package servicetest provides a service.TaxService fake for tests of the packages that use it
+package servicetest
+
+import (
+	"context"
+	"time"
+
+	"github.com/renanrt/lab-go-api/address"
+	"github.com/renanrt/lab-go-api/auth"
+	"github.com/renanrt/lab-go-api/geo"
+	"github.com/renanrt/lab-go-api/model"
+	"github.com/renanrt/lab-go-api/provider"
+	"github.com/renanrt/lab-go-api/service"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockTaxService is a service.TaxService where each call returns what was set up with On
+type MockTaxService struct {
+	mock.Mock
+}
+
+var _ service.TaxService = (*MockTaxService)(nil)
+
+// FindTaxGroups mocks service.TaxService.FindTaxGroups
+func (m *MockTaxService) FindTaxGroups(ctx context.Context, asOf time.Time, providerName string, addr address.Address) ([]*model.TaxGroup, error) {
+	args := m.Called(ctx, asOf, providerName, addr)
+	return taxGroups(args, 0), args.Error(1)
+}
+
+// FindTaxGroupsForPoint mocks service.TaxService.FindTaxGroupsForPoint
+func (m *MockTaxService) FindTaxGroupsForPoint(ctx context.Context, asOf time.Time, providerName, country string, point geo.Point) ([]*model.TaxGroup, error) {
+	args := m.Called(ctx, asOf, providerName, country, point)
+	return taxGroups(args, 0), args.Error(1)
+}
+
+// FindTaxGroupsBatch mocks service.TaxService.FindTaxGroupsBatch
+func (m *MockTaxService) FindTaxGroupsBatch(ctx context.Context, asOf time.Time, providerName, retailerId string, addresses []address.Address) ([]*service.BatchResult, error) {
+	args := m.Called(ctx, asOf, providerName, retailerId, addresses)
+	results, _ := args.Get(0).([]*service.BatchResult)
+	return results, args.Error(1)
+}
+
+// FindTaxGroupsBatchFunc mocks service.TaxService.FindTaxGroupsBatchFunc.
+// The results set up with the first return value are sent to fn.
+func (m *MockTaxService) FindTaxGroupsBatchFunc(ctx context.Context, asOf time.Time, providerName, retailerId string, addresses []address.Address, fn func(*service.BatchResult)) error {
+	args := m.Called(ctx, asOf, providerName, retailerId, addresses)
+	results, _ := args.Get(0).([]*service.BatchResult)
+	for _, result := range results {
+		fn(result)
+	}
+	return args.Error(1)
+}
+
+// GetRateChanges mocks service.TaxService.GetRateChanges
+func (m *MockTaxService) GetRateChanges(ctx context.Context, from, to time.Time, country, state, retailerID string) ([]*model.RateChange, error) {
+	args := m.Called(ctx, from, to, country, state, retailerID)
+	changes, _ := args.Get(0).([]*model.RateChange)
+	return changes, args.Error(1)
+}
+
+// ReconcileTaxes mocks service.TaxService.ReconcileTaxes
+func (m *MockTaxService) ReconcileTaxes(ctx context.Context, asOf time.Time, retailerID string, groups ...*model.TaxGroup) error {
+	args := m.Called(ctx, asOf, retailerID, groups)
+	return args.Error(0)
+}
+
+// AcceptTaxes mocks service.TaxService.AcceptTaxes
+func (m *MockTaxService) AcceptTaxes(ctx context.Context, asOf time.Time, retailerID string, taxGroup *model.TaxGroup) ([]*model.Tax, error) {
+	args := m.Called(ctx, asOf, retailerID, taxGroup)
+	return taxes(args, 0), args.Error(1)
+}
+
+// GetRetailerTaxes mocks service.TaxService.GetRetailerTaxes
+func (m *MockTaxService) GetRetailerTaxes(ctx context.Context, asOf time.Time, retailerID string) ([]*model.Tax, error) {
+	args := m.Called(ctx, asOf, retailerID)
+	return taxes(args, 0), args.Error(1)
+}
+
+// SaveRetailerTax mocks service.TaxService.SaveRetailerTax
+func (m *MockTaxService) SaveRetailerTax(ctx context.Context, retailerID string, tax *model.Tax) error {
+	args := m.Called(ctx, retailerID, tax)
+	return args.Error(0)
+}
+
+// SubmitJob mocks service.TaxService.SubmitJob
+func (m *MockTaxService) SubmitJob(ctx context.Context, retailerID, providerName string, asOf time.Time, items []*model.JobItem) (*model.Job, error) {
+	args := m.Called(ctx, retailerID, providerName, asOf, items)
+	return job(args, 0), args.Error(1)
+}
+
+// GetJob mocks service.TaxService.GetJob
+func (m *MockTaxService) GetJob(ctx context.Context, retailerID, id string) (*model.Job, error) {
+	args := m.Called(ctx, retailerID, id)
+	return job(args, 0), args.Error(1)
+}
+
+// CancelJob mocks service.TaxService.CancelJob
+func (m *MockTaxService) CancelJob(ctx context.Context, retailerID, id string) (*model.Job, error) {
+	args := m.Called(ctx, retailerID, id)
+	return job(args, 0), args.Error(1)
+}
+
+// CreateAPIKey mocks service.TaxService.CreateAPIKey
+func (m *MockTaxService) CreateAPIKey(ctx context.Context, caller *auth.Principal, name string, scopes []model.Scope) (*model.APIKey, string, error) {
+	args := m.Called(ctx, caller, name, scopes)
+	return apiKey(args, 0), args.String(1), args.Error(2)
+}
+
+// ListAPIKeys mocks service.TaxService.ListAPIKeys
+func (m *MockTaxService) ListAPIKeys(ctx context.Context, retailerID string) ([]*model.APIKey, error) {
+	args := m.Called(ctx, retailerID)
+	keys, _ := args.Get(0).([]*model.APIKey)
+	return keys, args.Error(1)
+}
+
+// RotateAPIKey mocks service.TaxService.RotateAPIKey
+func (m *MockTaxService) RotateAPIKey(ctx context.Context, caller *auth.Principal, id string) (*model.APIKey, string, error) {
+	args := m.Called(ctx, caller, id)
+	return apiKey(args, 0), args.String(1), args.Error(2)
+}
+
+// RevokeAPIKey mocks service.TaxService.RevokeAPIKey
+func (m *MockTaxService) RevokeAPIKey(ctx context.Context, retailerID, id string) (*model.APIKey, error) {
+	args := m.Called(ctx, retailerID, id)
+	return apiKey(args, 0), args.Error(1)
+}
+
+// LookupAPIKey mocks service.TaxService.LookupAPIKey
+func (m *MockTaxService) LookupAPIKey(ctx context.Context, secret string) (*model.APIKey, error) {
+	args := m.Called(ctx, secret)
+	return apiKey(args, 0), args.Error(1)
+}
+
+// PurgeCache mocks service.TaxService.PurgeCache
+func (m *MockTaxService) PurgeCache() int {
+	return m.Called().Int(0)
+}
+
+// ProviderStatus mocks service.TaxService.ProviderStatus
+func (m *MockTaxService) ProviderStatus() []*provider.Status {
+	statuses, _ := m.Called().Get(0).([]*provider.Status)
+	return statuses
+}
+
+// MigrateRateChanges mocks service.TaxService.MigrateRateChanges
+func (m *MockTaxService) MigrateRateChanges(ctx context.Context, changes []*model.RateChange) (*service.Migration, error) {
+	args := m.Called(ctx, changes)
+	migration, _ := args.Get(0).(*service.Migration)
+	return migration, args.Error(1)
+}
+
+// the helpers below allow setting up nil return values
+
+func taxGroups(args mock.Arguments, i int) []*model.TaxGroup {
+	groups, _ := args.Get(i).([]*model.TaxGroup)
+	return groups
+}
+
+func taxes(args mock.Arguments, i int) []*model.Tax {
+	t, _ := args.Get(i).([]*model.Tax)
+	return t
+}
+
+func job(args mock.Arguments, i int) *model.Job {
+	j, _ := args.Get(i).(*model.Job)
+	return j
+}
+
+func apiKey(args mock.Arguments, i int) *model.APIKey {
+	key, _ := args.Get(i).(*model.APIKey)
+	return key
+}
@@ -0,0 +1,161 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/renanrt/lab-go-api/apperrors"
+	"github.com/renanrt/lab-go-api/model"
+	"github.com/renanrt/lab-go-api/store"
+)
+
+var (
+	// ErrInvalidTax is returned when a tax is saved without name or type
+	ErrInvalidTax = apperrors.Validation("name and type are mandatory", nil)
+	// ErrEmptyTaxGroup is returned when a tax group without rates is accepted
+	ErrEmptyTaxGroup = apperrors.Validation("the tax group has no rates", nil)
+	// ErrUnknownVendTaxID is returned when an accepted tax group links taxes that the retailer didn't save
+	ErrUnknownVendTaxID = apperrors.Validation("the tax group has vend tax ids of taxes that are not saved", nil)
+)
+
+// GetRetailerTaxes returns the taxes saved by a retailer that are effective at the asOf date.
+// Scheduled taxes are returned once their valid_from is reached, and expired ones are left out.
+func (service *Service) GetRetailerTaxes(ctx context.Context, asOf time.Time, retailerID string) ([]*model.Tax, error) {
+	taxes, err := service.store().GetTaxes(ctx, retailerID)
+	if err != nil {
+		return nil, err
+	}
+	return model.ActiveTaxes(taxes, asOf), nil
+}
+
+// SaveRetailerTax creates or updates a tax of the retailer.
+// Retailers can only update their own taxes, others return store.ErrNotFound.
+func (service *Service) SaveRetailerTax(ctx context.Context, retailerID string, tax *model.Tax) error {
+	if tax.Name == "" || tax.Type == "" {
+		return ErrInvalidTax
+	}
+
+	if tax.ID != "" {
+		taxes, err := service.store().GetTaxes(ctx, retailerID)
+		if err != nil {
+			return err
+		}
+		if !containsTaxID(taxes, tax.ID) {
+			return store.ErrNotFound
+		}
+	}
+
+	tax.RetailerID = retailerID
+	defer service.resetAffectedRetailers()
+	return service.store().SaveTax(ctx, tax)
+}
+
+// ReconcileTaxes links the tax rates of the groups with the taxes saved by the retailer that are
+// effective at the asOf date: the rates that match a saved tax get its vend tax id, so the POS
+// reuses the existing taxes. The taxes of the retailer are indexed once for all the groups.
+func (service *Service) ReconcileTaxes(ctx context.Context, asOf time.Time, retailerID string, taxGroups ...*model.TaxGroup) error {
+	idx, err := service.retailerTaxIndex(ctx, asOf, retailerID)
+	if err != nil {
+		return err
+	}
+	reconcile(idx, taxGroups)
+	return nil
+}
+
+// retailerTaxIndex indexes the taxes of the retailer effective at the asOf date. Requests
+// without retailer have an empty index.
+func (service *Service) retailerTaxIndex(ctx context.Context, asOf time.Time, retailerID string) (*model.TaxIndex, error) {
+	if retailerID == "" {
+		return model.NewTaxIndex(nil), nil
+	}
+	taxes, err := service.GetRetailerTaxes(ctx, asOf, retailerID)
+	if err != nil {
+		return nil, err
+	}
+	return model.NewTaxIndex(taxes), nil
+}
+
+// reconcile sets the vend tax ids of the rates that match a tax of the index
+func reconcile(idx *model.TaxIndex, taxGroups []*model.TaxGroup) {
+	for _, taxGroup := range taxGroups {
+		for _, tr := range taxGroup.Rates {
+			if tax := findTax(idx, tr); tax != nil && tax.VendTaxID != "" {
+				tr.VendTaxID = tax.VendTaxID
+			}
+		}
+	}
+}
+
+// AcceptTaxes saves the tax rates of a group accepted by the retailer. Rates that match a saved
+// tax effective at the asOf date are kept, and the new ones are saved with the state tax of the group as parent: the state
+// tax is matched or saved first, so the parent is always a tax saved by the retailer.
+// It returns the saved taxes of the group, in the order of its rates.
+func (service *Service) AcceptTaxes(ctx context.Context, asOf time.Time, retailerID string, taxGroup *model.TaxGroup) ([]*model.Tax, error) {
+	if len(taxGroup.Rates) == 0 {
+		return nil, ErrEmptyTaxGroup
+	}
+
+	taxes, err := service.GetRetailerTaxes(ctx, asOf, retailerID)
+	if err != nil {
+		return nil, err
+	}
+	idx := model.NewTaxIndex(taxes)
+	// The vend tax ids of the group are set by ReconcileTaxes from the taxes of the retailer
+	for _, tr := range taxGroup.Rates {
+		if tr.VendTaxID != "" && !idx.ContainsVendTaxID(tr.VendTaxID) {
+			return nil, ErrUnknownVendTaxID
+		}
+	}
+
+	defer service.resetAffectedRetailers()
+	accept := func(tr *model.TaxRate, parentID string) (*model.Tax, error) {
+		if tax := findTax(idx, tr); tax != nil {
+			return tax, nil
+		}
+		tax := tr.ToTax()
+		tax.RetailerID = retailerID
+		tax.ParentId = parentID
+		if err := service.store().SaveTax(ctx, tax); err != nil {
+			return nil, err
+		}
+		return tax, nil
+	}
+
+	accepted := make([]*model.Tax, len(taxGroup.Rates))
+	parentID := ""
+	for i, tr := range taxGroup.Rates {
+		if !model.IsSameType(tr.Type, model.TaxTypeState) {
+			continue
+		}
+		if accepted[i], err = accept(tr, ""); err != nil {
+			return nil, err
+		}
+		if parentID == "" {
+			parentID = accepted[i].ID
+		}
+	}
+	for i, tr := range taxGroup.Rates {
+		if accepted[i] != nil {
+			continue
+		}
+		if accepted[i], err = accept(tr, parentID); err != nil {
+			return nil, err
+		}
+	}
+	return accepted, nil
+}
+
+// findTax returns the indexed tax that best matches the tax rate, or nil
+func findTax(idx *model.TaxIndex, tr *model.TaxRate) *model.Tax {
+	tax, _ := idx.Match(tr, model.DefaultMatcher)
+	return tax
+}
+
+func containsTaxID(taxes []*model.Tax, id string) bool {
+	for _, tax := range taxes {
+		if tax.ID == id {
+			return true
+		}
+	}
+	return false
+}
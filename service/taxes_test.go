@@ -0,0 +1,134 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/renanrt/lab-go-api/model"
+	"github.com/renanrt/lab-go-api/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSaveRetailerTax(t *testing.T) {
+	ctx := context.Background()
+	service := &Service{Store: store.NewMemoryStore()}
+
+	tax := &model.Tax{Name: "California", Rate: 0.0725, Type: model.TaxTypeState}
+	assert.NoError(t, service.SaveRetailerTax(ctx, "retailer-1", tax))
+	assert.NotEmpty(t, tax.ID)
+	assert.Equal(t, "retailer-1", tax.RetailerID)
+
+	// another retailer can't update it
+	other := &model.Tax{ID: tax.ID, Name: "California", Rate: 0.075, Type: model.TaxTypeState}
+	assert.Equal(t, store.ErrNotFound, service.SaveRetailerTax(ctx, "retailer-2", other))
+
+	tax.Rate = 0.075
+	assert.NoError(t, service.SaveRetailerTax(ctx, "retailer-1", tax))
+	taxes, _ := service.GetRetailerTaxes(ctx, time.Now(), "retailer-1")
+	assert.Len(t, taxes, 1)
+	assert.Equal(t, 0.075, taxes[0].Rate)
+
+	assert.Equal(t, ErrInvalidTax, service.SaveRetailerTax(ctx, "retailer-1", &model.Tax{Rate: 0.01}))
+}
+
+func TestAcceptAndReconcileTaxes(t *testing.T) {
+	ctx := context.Background()
+	service := &Service{Store: store.NewMemoryStore()}
+
+	state := &model.Tax{Name: "California", Rate: 0.0725, Type: model.TaxTypeState, VendTaxID: "vend-ca"}
+	assert.NoError(t, service.SaveRetailerTax(ctx, "retailer-1", state))
+
+	taxGroup := &model.TaxGroup{}
+	taxGroup.AddTaxRate(&model.TaxRate{Name: "California", Rate: 0.0725, Type: model.TaxTypeState, VendTaxID: "vend-ca"})
+	taxGroup.AddTaxRate(model.NewTaxRate(model.TaxTypeCity, "Santa Monica", 0.01))
+
+	accepted, err := service.AcceptTaxes(ctx, time.Now(), "retailer-1", taxGroup)
+	assert.NoError(t, err)
+	assert.Len(t, accepted, 2)
+	assert.Equal(t, state.ID, accepted[0].ID)
+	assert.Equal(t, state.ID, accepted[1].ParentId)
+	assert.Empty(t, accepted[0].ParentId)
+
+	// accepting it again doesn't duplicate the taxes
+	_, err = service.AcceptTaxes(ctx, time.Now(), "retailer-1", taxGroup)
+	assert.NoError(t, err)
+	taxes, _ := service.GetRetailerTaxes(ctx, time.Now(), "retailer-1")
+	assert.Len(t, taxes, 2)
+
+	recommended := &model.TaxGroup{}
+	recommended.AddTaxRate(model.NewTaxRate(model.TaxTypeState, "CALIFORNIA", 0.0725))
+	recommended.AddTaxRate(model.NewTaxRate(model.TaxTypeCounty, "Los Angeles County", 0.0025))
+	assert.NoError(t, service.ReconcileTaxes(ctx, time.Now(), "retailer-1", recommended))
+	assert.Equal(t, "vend-ca", recommended.Rates[0].VendTaxID)
+	assert.Empty(t, recommended.Rates[1].VendTaxID)
+
+	_, err = service.AcceptTaxes(ctx, time.Now(), "retailer-1", &model.TaxGroup{})
+	assert.Equal(t, ErrEmptyTaxGroup, err)
+}
+
+// tests that a state tax saved in the same call is the parent of the other taxes
+func TestAcceptTaxesNewParent(t *testing.T) {
+	ctx := context.Background()
+	service := &Service{Store: store.NewMemoryStore()}
+
+	taxGroup := &model.TaxGroup{}
+	taxGroup.AddTaxRate(model.NewTaxRate(model.TaxTypeCity, "Austin", 0.01))
+	taxGroup.AddTaxRate(model.NewTaxRate(model.TaxTypeState, "Texas", 0.0625))
+
+	accepted, err := service.AcceptTaxes(ctx, time.Now(), "retailer-1", taxGroup)
+	assert.NoError(t, err)
+	assert.Len(t, accepted, 2)
+	assert.Equal(t, "Austin", accepted[0].Name)
+	assert.NotEmpty(t, accepted[1].ID)
+	assert.Equal(t, accepted[1].ID, accepted[0].ParentId)
+}
+
+// tests that the vend tax ids of the group must be of taxes saved by the retailer
+func TestAcceptTaxesUnknownParent(t *testing.T) {
+	ctx := context.Background()
+	service := &Service{Store: store.NewMemoryStore()}
+	assert.NoError(t, service.SaveRetailerTax(ctx, "retailer-2", &model.Tax{Name: "Texas", Rate: 0.0625, Type: model.TaxTypeState, VendTaxID: "vend-tx"}))
+
+	taxGroup := &model.TaxGroup{}
+	taxGroup.AddTaxRate(&model.TaxRate{Name: "Texas", Rate: 0.0625, Type: model.TaxTypeState, VendTaxID: "vend-tx"})
+	taxGroup.AddTaxRate(model.NewTaxRate(model.TaxTypeCity, "Austin", 0.01))
+
+	_, err := service.AcceptTaxes(ctx, time.Now(), "retailer-1", taxGroup)
+	assert.Equal(t, ErrUnknownVendTaxID, err)
+	taxes, _ := service.GetRetailerTaxes(ctx, time.Now(), "retailer-1")
+	assert.Empty(t, taxes)
+}
+
+// tests that the saved taxes are only matched while they are effective, so a scheduled tax
+// replaces the current one once its valid_from is reached
+func TestScheduledRetailerTaxes(t *testing.T) {
+	ctx := context.Background()
+	service := &Service{Store: store.NewMemoryStore()}
+	july := time.Date(2018, time.July, 1, 0, 0, 0, 0, time.UTC)
+
+	current := &model.Tax{Name: "California", Rate: 0.0725, Type: model.TaxTypeState, VendTaxID: "vend-ca-2018"}
+	current.ValidTo = &july
+	scheduled := &model.Tax{Name: "California", Rate: 0.0725, Type: model.TaxTypeState, VendTaxID: "vend-ca-2018-07"}
+	scheduled.ValidFrom = &july
+	assert.NoError(t, service.SaveRetailerTax(ctx, "retailer-1", current))
+	assert.NoError(t, service.SaveRetailerTax(ctx, "retailer-1", scheduled))
+
+	taxes, err := service.GetRetailerTaxes(ctx, july.AddDate(0, -1, 0), "retailer-1")
+	assert.NoError(t, err)
+	assert.Equal(t, []*model.Tax{current}, taxes)
+
+	recommended := &model.TaxGroup{}
+	recommended.AddTaxRate(model.NewTaxRate(model.TaxTypeState, "California", 0.0725))
+	assert.NoError(t, service.ReconcileTaxes(ctx, july.AddDate(0, -1, 0), "retailer-1", recommended))
+	assert.Equal(t, "vend-ca-2018", recommended.Rates[0].VendTaxID)
+	assert.NoError(t, service.ReconcileTaxes(ctx, july, "retailer-1", recommended))
+	assert.Equal(t, "vend-ca-2018-07", recommended.Rates[0].VendTaxID)
+
+	// once the scheduled tax is effective, the expired one is not the parent of the new taxes
+	recommended.AddTaxRate(model.NewTaxRate(model.TaxTypeCity, "Santa Monica", 0.01))
+	accepted, err := service.AcceptTaxes(ctx, july, "retailer-1", recommended)
+	assert.NoError(t, err)
+	assert.Equal(t, scheduled.ID, accepted[0].ID)
+	assert.Equal(t, scheduled.ID, accepted[1].ParentId)
+}
@@ -0,0 +1,262 @@
+package store
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/renanrt/lab-go-api/model"
+)
+
+// FileStore is a MemoryStore that saves a snapshot to a JSON file after every change,
+// so the data survives process restarts. Used for development and single instance setups.
+//
+// The jobs are saved apart from the snapshot, in the directory path + ".jobs", with a file
+// for the job, one for its items and one with its results. The items are written once and the
+// results are appended, so saving the progress of a job doesn't rewrite what was already saved.
+//
+// TouchAPIKey doesn't write the snapshot, so authenticating doesn't rewrite the file. The last
+// use of the keys is saved with the next change.
+type FileStore struct {
+	*MemoryStore
+	path string
+
+	// mu serializes the writes to the files
+	mu sync.Mutex
+	// savedResults is the number of results in the results file of each job
+	savedResults map[string]int
+}
+
+type snapshot struct {
+	Taxes   map[string][]*model.Tax  `json:"taxes"`
+	APIKeys map[string]*model.APIKey `json:"api_keys"`
+}
+
+const (
+	jobExt     = ".job.json"
+	itemsExt   = ".items.json"
+	resultsExt = ".results.jsonl"
+)
+
+// OpenFileStore loads the snapshot and the jobs from the files, or creates an empty store if they don't exist
+func OpenFileStore(path string) (*FileStore, error) {
+	s := &FileStore{MemoryStore: NewMemoryStore(), path: path, savedResults: map[string]int{}}
+	if err := s.loadJobs(); err != nil {
+		return nil, err
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	snap := snapshot{}
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return nil, err
+	}
+	if snap.Taxes != nil {
+		s.taxes = snap.Taxes
+	}
+	for id, key := range snap.APIKeys {
+		s.apiKeys[id] = key
+		s.keyIDs[key.Hash] = id
+	}
+	return s, nil
+}
+
+// SaveTax saves the tax and the snapshot
+func (s *FileStore) SaveTax(ctx context.Context, tax *model.Tax) error {
+	if err := s.MemoryStore.SaveTax(ctx, tax); err != nil {
+		return err
+	}
+	return s.flush()
+}
+
+// SaveJob saves the job and its files. Only the results that aren't in the results file yet are written
+func (s *FileStore) SaveJob(ctx context.Context, job *model.Job) error {
+	if err := s.MemoryStore.SaveJob(ctx, job); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	dir := s.jobsDir()
+	saved, ok := s.savedResults[job.ID]
+	if !ok {
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return err
+		}
+		if err := writeJSON(filepath.Join(dir, job.ID+itemsExt), job.Items); err != nil {
+			return err
+		}
+	}
+	if saved < len(job.Results) {
+		if err := appendResults(filepath.Join(dir, job.ID+resultsExt), job.Results[saved:]); err != nil {
+			return err
+		}
+		saved = len(job.Results)
+	}
+	s.savedResults[job.ID] = saved
+
+	// The job is written after its results, so a job is never loaded with missing results
+	return writeJSON(filepath.Join(dir, job.ID+jobExt), job.Summary())
+}
+
+// SaveAPIKey saves the API key and the snapshot
+func (s *FileStore) SaveAPIKey(ctx context.Context, key *model.APIKey) error {
+	if err := s.MemoryStore.SaveAPIKey(ctx, key); err != nil {
+		return err
+	}
+	return s.flush()
+}
+
+// jobsDir is the directory of the job files
+func (s *FileStore) jobsDir() string {
+	return s.path + ".jobs"
+}
+
+// flush writes the snapshot of the taxes and API keys
+func (s *FileStore) flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.MemoryStore.mu.RLock()
+	data, err := json.Marshal(snapshot{Taxes: s.taxes, APIKeys: s.apiKeys})
+	s.MemoryStore.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+	return writeFile(s.path, data)
+}
+
+// loadJobs reads the jobs from their files. The results written after the last save of a job
+// are dropped, and cut from the results file so the next ones are appended after the saved ones
+func (s *FileStore) loadJobs() error {
+	paths, err := filepath.Glob(filepath.Join(s.jobsDir(), "*"+jobExt))
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		id := strings.TrimSuffix(filepath.Base(path), jobExt)
+		job := &model.Job{}
+		if err := readJSON(path, job); err != nil {
+			return err
+		}
+		if err := readJSON(filepath.Join(s.jobsDir(), id+itemsExt), &job.Items); err != nil {
+			return err
+		}
+		if job.Results, err = readResults(filepath.Join(s.jobsDir(), id+resultsExt), job.Processed); err != nil {
+			return err
+		}
+		s.jobs[id] = job
+		s.savedResults[id] = len(job.Results)
+	}
+	return nil
+}
+
+// writeJSON writes the value as JSON with writeFile
+func writeJSON(path string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return writeFile(path, data)
+}
+
+// writeFile writes the data to a temporary file, and renames it so the file is never left half written
+func writeFile(path string, data []byte) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path))
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func readJSON(path string, v interface{}) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// appendResults adds the results to the file, one JSON per line
+func appendResults(path string, results []*model.JobResult) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for _, result := range results {
+		if err := enc.Encode(result); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// readResults reads up to max results from the file, and truncates the file after them
+func readResults(path string, max int) ([]*model.JobResult, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	results := []*model.JobResult{}
+	r := bufio.NewReader(f)
+	var size int64
+	for len(results) < max {
+		line, err := r.ReadBytes('\n')
+		if err == io.EOF {
+			// a line without the new line wasn't completely written
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		result := &model.JobResult{}
+		if err := json.Unmarshal(line, result); err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+		size += int64(len(line))
+	}
+
+	if info, err := f.Stat(); err != nil {
+		return nil, err
+	} else if info.Size() > size {
+		if err := os.Truncate(path, size); err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
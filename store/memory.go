@@ -0,0 +1,250 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/renanrt/lab-go-api/model"
+)
+
+// MemoryStore is a Store that keeps everything in memory. Used for development and tests
+type MemoryStore struct {
+	mu      sync.RWMutex
+	taxes   map[string][]*model.Tax
+	jobs    map[string]*model.Job
+	apiKeys map[string]*model.APIKey
+	// keyIDs indexes the API key ids by hash, keys are looked up on every request
+	keyIDs map[string]string
+}
+
+// NewMemoryStore creates an empty MemoryStore
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		taxes:   map[string][]*model.Tax{},
+		jobs:    map[string]*model.Job{},
+		apiKeys: map[string]*model.APIKey{},
+		keyIDs:  map[string]string{},
+	}
+}
+
+// GetTaxes returns copies of the taxes of a retailer
+func (s *MemoryStore) GetTaxes(ctx context.Context, retailerID string) ([]*model.Tax, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	taxes := []*model.Tax{}
+	for _, tax := range s.taxes[retailerID] {
+		t := *tax
+		taxes = append(taxes, &t)
+	}
+	return taxes, nil
+}
+
+// SaveTax creates or updates a tax
+func (s *MemoryStore) SaveTax(ctx context.Context, tax *model.Tax) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if tax.RetailerID == "" {
+		return errors.New("retailer_id is mandatory")
+	}
+	if tax.ID == "" {
+		tax.ID = NewID()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t := *tax
+	taxes := s.taxes[tax.RetailerID]
+	for i, existing := range taxes {
+		if existing.ID == tax.ID {
+			taxes[i] = &t
+			return nil
+		}
+	}
+	s.taxes[tax.RetailerID] = append(taxes, &t)
+	return nil
+}
+
+// RetailerIDs returns the sorted ids of the retailers that have taxes
+func (s *MemoryStore) RetailerIDs(ctx context.Context) ([]string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	ids := make([]string, 0, len(s.taxes))
+	for id := range s.taxes {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// SaveJob creates or updates a job
+func (s *MemoryStore) SaveJob(ctx context.Context, job *model.Job) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if job.ID == "" {
+		job.ID = NewID()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.jobs[job.ID] = copyJob(job)
+	return nil
+}
+
+// GetJob returns a copy of a job
+func (s *MemoryStore) GetJob(ctx context.Context, id string) (*model.Job, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	job, ok := s.jobs[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return copyJob(job), nil
+}
+
+// UnfinishedJobs returns copies of the pending and running jobs, oldest first
+func (s *MemoryStore) UnfinishedJobs(ctx context.Context) ([]*model.Job, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	jobs := []*model.Job{}
+	for _, job := range s.jobs {
+		if !job.IsFinished() {
+			jobs = append(jobs, copyJob(job))
+		}
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].CreatedAt.Before(jobs[j].CreatedAt)
+	})
+	return jobs, nil
+}
+
+// copyJob copies the job and its lists, the items and results themselves are never modified
+func copyJob(job *model.Job) *model.Job {
+	c := *job
+	c.Items = append([]*model.JobItem(nil), job.Items...)
+	c.Results = append([]*model.JobResult(nil), job.Results...)
+	return &c
+}
+
+// SaveAPIKey creates or updates an API key
+func (s *MemoryStore) SaveAPIKey(ctx context.Context, key *model.APIKey) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if key.RetailerID == "" {
+		return errors.New("retailer_id is mandatory")
+	}
+	if key.ID == "" {
+		key.ID = NewID()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// a rotated key has a new hash
+	if existing, ok := s.apiKeys[key.ID]; ok {
+		delete(s.keyIDs, existing.Hash)
+	}
+	s.apiKeys[key.ID] = key.Copy()
+	s.keyIDs[key.Hash] = key.ID
+	return nil
+}
+
+// GetAPIKey returns a copy of an API key
+func (s *MemoryStore) GetAPIKey(ctx context.Context, id string) (*model.APIKey, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	key, ok := s.apiKeys[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return key.Copy(), nil
+}
+
+// GetAPIKeyByHash returns a copy of the API key with the hash
+func (s *MemoryStore) GetAPIKeyByHash(ctx context.Context, hash string) (*model.APIKey, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	key, ok := s.apiKeys[s.keyIDs[hash]]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return key.Copy(), nil
+}
+
+// TouchAPIKey sets the last use of the key, unless it was rotated or revoked
+func (s *MemoryStore) TouchAPIKey(ctx context.Context, id, hash string, t time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key, ok := s.apiKeys[id]
+	if !ok || key.Hash != hash || key.IsRevoked() {
+		return ErrNotFound
+	}
+	key.LastUsedAt = &t
+	return nil
+}
+
+// APIKeys returns copies of the API keys of a retailer, oldest first
+func (s *MemoryStore) APIKeys(ctx context.Context, retailerID string) ([]*model.APIKey, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	keys := []*model.APIKey{}
+	for _, key := range s.apiKeys {
+		if key.RetailerID == retailerID {
+			keys = append(keys, key.Copy())
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].CreatedAt.Before(keys[j].CreatedAt)
+	})
+	return keys, nil
+}
@@ -0,0 +1,191 @@
+package store
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/renanrt/lab-go-api/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryStoreSaveTax(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore()
+
+	tax := &model.Tax{RetailerID: "retailer-1", Name: "California", Rate: 0.0725, Type: model.TaxTypeState}
+	assert.NoError(t, s.SaveTax(ctx, tax))
+	assert.NotEmpty(t, tax.ID)
+
+	tax.Rate = 0.075
+	assert.NoError(t, s.SaveTax(ctx, tax))
+	assert.NoError(t, s.SaveTax(ctx, &model.Tax{RetailerID: "retailer-2", Name: "New York"}))
+	assert.Error(t, s.SaveTax(ctx, &model.Tax{Name: "No retailer"}))
+
+	taxes, err := s.GetTaxes(ctx, "retailer-1")
+	assert.NoError(t, err)
+	assert.Len(t, taxes, 1)
+	assert.Equal(t, 0.075, taxes[0].Rate)
+
+	ids, err := s.RetailerIDs(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"retailer-1", "retailer-2"}, ids)
+}
+
+func TestMemoryStoreJobs(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore()
+
+	job := &model.Job{RetailerID: "retailer-1", Status: model.JobStatusPending, Total: 2}
+	assert.NoError(t, s.SaveJob(ctx, job))
+	assert.NotEmpty(t, job.ID)
+	assert.NoError(t, s.SaveJob(ctx, &model.Job{RetailerID: "retailer-1", Status: model.JobStatusCompleted}))
+
+	saved, err := s.GetJob(ctx, job.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, saved.Total)
+
+	_, err = s.GetJob(ctx, "missing")
+	assert.Equal(t, ErrNotFound, err)
+
+	jobs, err := s.UnfinishedJobs(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, jobs, 1)
+	assert.Equal(t, job.ID, jobs[0].ID)
+}
+
+func TestMemoryStoreAPIKeys(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore()
+
+	key := &model.APIKey{RetailerID: "retailer-1", Name: "ecommerce", Hash: "hash-1", Scopes: []model.Scope{model.ScopeSearch}}
+	assert.NoError(t, s.SaveAPIKey(ctx, key))
+	assert.NotEmpty(t, key.ID)
+	assert.NoError(t, s.SaveAPIKey(ctx, &model.APIKey{RetailerID: "retailer-2", Hash: "hash-2"}))
+	assert.Error(t, s.SaveAPIKey(ctx, &model.APIKey{Hash: "hash-3"}))
+
+	found, err := s.GetAPIKeyByHash(ctx, "hash-1")
+	assert.NoError(t, err)
+	assert.Equal(t, key.ID, found.ID)
+	found.Scopes[0] = model.ScopeTaxesWrite
+
+	// rotating replaces the hash
+	key.Hash = "hash-1-rotated"
+	assert.NoError(t, s.SaveAPIKey(ctx, key))
+	_, err = s.GetAPIKeyByHash(ctx, "hash-1")
+	assert.Equal(t, ErrNotFound, err)
+
+	saved, err := s.GetAPIKey(ctx, key.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "hash-1-rotated", saved.Hash)
+	assert.Equal(t, []model.Scope{model.ScopeSearch}, saved.Scopes)
+
+	keys, err := s.APIKeys(ctx, "retailer-1")
+	assert.NoError(t, err)
+	assert.Len(t, keys, 1)
+
+	_, err = s.GetAPIKey(ctx, "missing")
+	assert.Equal(t, ErrNotFound, err)
+
+	// only the current hash of a valid key is touched
+	now := time.Date(2018, time.July, 1, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, ErrNotFound, s.TouchAPIKey(ctx, key.ID, "hash-1", now))
+	assert.NoError(t, s.TouchAPIKey(ctx, key.ID, "hash-1-rotated", now))
+	saved, _ = s.GetAPIKey(ctx, key.ID)
+	assert.Equal(t, now, *saved.LastUsedAt)
+	key.RevokedAt = &now
+	assert.NoError(t, s.SaveAPIKey(ctx, key))
+	assert.Equal(t, ErrNotFound, s.TouchAPIKey(ctx, key.ID, "hash-1-rotated", now))
+	assert.Equal(t, ErrNotFound, s.TouchAPIKey(ctx, "missing", "hash-1-rotated", now))
+}
+
+// tests that the data survives reopening the file store
+func TestFileStore(t *testing.T) {
+	ctx := context.Background()
+	dir, err := ioutil.TempDir("", "store")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "store.json")
+
+	s, err := OpenFileStore(path)
+	assert.NoError(t, err)
+	job := &model.Job{RetailerID: "retailer-1", Status: model.JobStatusRunning, Processed: 100}
+	assert.NoError(t, s.SaveJob(ctx, job))
+	assert.NoError(t, s.SaveTax(ctx, &model.Tax{RetailerID: "retailer-1", Name: "California"}))
+	assert.NoError(t, s.SaveAPIKey(ctx, &model.APIKey{RetailerID: "retailer-1", Hash: "hash-1"}))
+
+	s, err = OpenFileStore(path)
+	assert.NoError(t, err)
+	jobs, err := s.UnfinishedJobs(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, jobs, 1)
+	assert.Equal(t, 100, jobs[0].Processed)
+
+	taxes, err := s.GetTaxes(ctx, "retailer-1")
+	assert.NoError(t, err)
+	assert.Len(t, taxes, 1)
+
+	key, err := s.GetAPIKeyByHash(ctx, "hash-1")
+	assert.NoError(t, err)
+	assert.Equal(t, "retailer-1", key.RetailerID)
+}
+
+// tests that the results of a job are appended to its file, and the ones written after the last save are dropped
+func TestFileStoreJobResults(t *testing.T) {
+	ctx := context.Background()
+	dir, err := ioutil.TempDir("", "store")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "store.json")
+
+	s, err := OpenFileStore(path)
+	assert.NoError(t, err)
+	job := &model.Job{RetailerID: "retailer-1", Status: model.JobStatusRunning, Total: 3,
+		Items: []*model.JobItem{{ID: "1"}, {ID: "2"}, {ID: "3"}}}
+	assert.NoError(t, s.SaveJob(ctx, job))
+	job.Results = append(job.Results, &model.JobResult{ID: "1", Status: 200})
+	job.Processed = 1
+	assert.NoError(t, s.SaveJob(ctx, job))
+	job.Results = append(job.Results, &model.JobResult{ID: "2", Status: 404})
+	job.Processed = 2
+	assert.NoError(t, s.SaveJob(ctx, job))
+
+	resultsPath := filepath.Join(path+".jobs", job.ID+".results.jsonl")
+	data, err := ioutil.ReadFile(resultsPath)
+	assert.NoError(t, err)
+	assert.Equal(t, "{\"id\":\"1\",\"status\":200}\n{\"id\":\"2\",\"status\":404}\n", string(data))
+
+	// a result appended before the process stopped, without saving the job
+	f, err := os.OpenFile(resultsPath, os.O_WRONLY|os.O_APPEND, 0600)
+	assert.NoError(t, err)
+	_, err = f.WriteString("{\"id\":\"3\",\"status\":200}\n")
+	assert.NoError(t, err)
+	assert.NoError(t, f.Close())
+
+	s, err = OpenFileStore(path)
+	assert.NoError(t, err)
+	saved, err := s.GetJob(ctx, job.ID)
+	assert.NoError(t, err)
+	assert.Len(t, saved.Items, 3)
+	assert.Equal(t, []*model.JobResult{{ID: "1", Status: 200}, {ID: "2", Status: 404}}, saved.Results)
+
+	saved.Results = append(saved.Results, &model.JobResult{ID: "3", Status: 300})
+	saved.Processed = 3
+	assert.NoError(t, s.SaveJob(ctx, saved))
+	data, err = ioutil.ReadFile(resultsPath)
+	assert.NoError(t, err)
+	assert.Equal(t, "{\"id\":\"1\",\"status\":200}\n{\"id\":\"2\",\"status\":404}\n{\"id\":\"3\",\"status\":300}\n", string(data))
+}
+
+func TestMemoryStoreContext(t *testing.T) {
+	s := NewMemoryStore()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	assert.Equal(t, context.Canceled, s.SaveTax(ctx, &model.Tax{RetailerID: "retailer-1", Name: "California"}))
+	_, err := s.GetTaxes(ctx, "retailer-1")
+	assert.Equal(t, context.Canceled, err)
+}
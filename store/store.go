@@ -0,0 +1,54 @@
+package store
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/renanrt/lab-go-api/model"
+)
+
+// Store persists the taxes saved by the retailers.
+// Implementations backed by a database stop the queries when the context is done.
+type Store interface {
+	// GetTaxes returns all taxes of a retailer
+	GetTaxes(ctx context.Context, retailerID string) ([]*model.Tax, error)
+	// SaveTax creates or updates a tax. An ID is generated for new taxes
+	SaveTax(ctx context.Context, tax *model.Tax) error
+	// RetailerIDs returns the ids of all retailers that have saved taxes
+	RetailerIDs(ctx context.Context) ([]string, error)
+
+	// SaveJob creates or updates a bulk lookup job. An ID is generated for new jobs
+	SaveJob(ctx context.Context, job *model.Job) error
+	// GetJob returns a job by id, or ErrNotFound
+	GetJob(ctx context.Context, id string) (*model.Job, error)
+	// UnfinishedJobs returns the jobs that are pending or running, to resume them after a restart
+	UnfinishedJobs(ctx context.Context) ([]*model.Job, error)
+
+	// SaveAPIKey creates or updates an API key. An ID is generated for new keys
+	SaveAPIKey(ctx context.Context, key *model.APIKey) error
+	// GetAPIKey returns an API key by id, or ErrNotFound
+	GetAPIKey(ctx context.Context, id string) (*model.APIKey, error)
+	// GetAPIKeyByHash returns the API key with the hash, or ErrNotFound
+	GetAPIKeyByHash(ctx context.Context, hash string) (*model.APIKey, error)
+	// TouchAPIKey sets when the key was last used. It returns ErrNotFound when the key
+	// doesn't have the hash anymore or is revoked, like after a concurrent rotation
+	TouchAPIKey(ctx context.Context, id, hash string, t time.Time) error
+	// APIKeys returns all API keys of a retailer
+	APIKeys(ctx context.Context, retailerID string) ([]*model.APIKey, error)
+}
+
+// ErrNotFound is returned when a record doesn't exist
+var ErrNotFound = errors.New("not found")
+
+// NewID generates a random id for a stored record
+func NewID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		// crypto/rand only fails if the OS can't provide randomness
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}